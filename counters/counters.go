@@ -0,0 +1,244 @@
+// Package counters compares ways of sharing a counter between goroutines.
+//
+// The Unsafe counter is deliberately racy: it loses updates and is reported
+// by the race detector (go run -race). All the others are exact.
+package counters
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Counter is incremented concurrently by several workers
+type Counter interface {
+	Name() string
+	// Inc adds one. worker identifies the calling goroutine.
+	Inc(worker int)
+	Value() uint64
+}
+
+// Unsafe uses a plain increment without any synchronization
+type Unsafe struct {
+	n uint64
+}
+
+// Name of the counter
+func (c *Unsafe) Name() string { return "plain increment" }
+
+// Inc adds one, but concurrent increments may be lost
+func (c *Unsafe) Inc(worker int) { c.n++ }
+
+// Value returns the (possibly stale) total
+func (c *Unsafe) Value() uint64 { return c.n }
+
+// Atomic uses atomic.Uint64
+type Atomic struct {
+	n atomic.Uint64
+}
+
+// Name of the counter
+func (c *Atomic) Name() string { return "atomic.Uint64" }
+
+// Inc adds one
+func (c *Atomic) Inc(worker int) { c.n.Add(1) }
+
+// Value returns the total
+func (c *Atomic) Value() uint64 { return c.n.Load() }
+
+// Mutex guards a plain integer with sync.Mutex
+type Mutex struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+// Name of the counter
+func (c *Mutex) Name() string { return "sync.Mutex" }
+
+// Inc adds one
+func (c *Mutex) Inc(worker int) {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+// Value returns the total
+func (c *Mutex) Value() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// shard is padded to its own cache line so shards don't slow each other down
+type shard struct {
+	n atomic.Uint64
+	_ [56]byte
+}
+
+// Sharded keeps one counter per CPU. Workers only touch their own shard, and
+// Value adds them all up.
+type Sharded struct {
+	shards []shard
+}
+
+// NewSharded creates a counter with one shard per CPU
+func NewSharded() *Sharded {
+	return &Sharded{shards: make([]shard, runtime.GOMAXPROCS(0))}
+}
+
+// Name of the counter
+func (c *Sharded) Name() string { return "sharded per CPU" }
+
+// Inc adds one to the shard of worker
+func (c *Sharded) Inc(worker int) { c.shards[worker%len(c.shards)].n.Add(1) }
+
+// Value returns the sum of all shards
+func (c *Sharded) Value() uint64 {
+	var total uint64
+	for i := range c.shards {
+		total += c.shards[i].n.Load()
+	}
+	return total
+}
+
+// Channel keeps the counter inside a single goroutine which receives
+// increments and queries through channels
+type Channel struct {
+	inc  chan struct{}
+	get  chan chan uint64
+	quit chan struct{}
+}
+
+// NewChannel starts the goroutine owning the counter. Close stops it.
+func NewChannel() *Channel {
+	c := &Channel{
+		inc:  make(chan struct{}, 128),
+		get:  make(chan chan uint64),
+		quit: make(chan struct{}),
+	}
+	go func() {
+		var n uint64
+		for {
+			select {
+			case <-c.inc:
+				n++
+			case resp := <-c.get:
+				// drain pending increments so the answer is exact
+				for len(c.inc) > 0 {
+					<-c.inc
+					n++
+				}
+				resp <- n
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Name of the counter
+func (c *Channel) Name() string { return "channel owned" }
+
+// Inc sends an increment to the owner
+func (c *Channel) Inc(worker int) { c.inc <- struct{}{} }
+
+// Value asks the owner for the total
+func (c *Channel) Value() uint64 {
+	resp := make(chan uint64)
+	c.get <- resp
+	return <-resp
+}
+
+// Close stops the owner goroutine
+func (c *Channel) Close() {
+	close(c.quit)
+}
+
+// All returns a fresh instance of every counter, optionally including Unsafe
+func All(includeUnsafe bool) []Counter {
+	list := []Counter{&Atomic{}, &Mutex{}, NewSharded(), NewChannel()}
+	if includeUnsafe {
+		list = append([]Counter{&Unsafe{}}, list...)
+	}
+	return list
+}
+
+// Result of running a workload on one counter
+type Result struct {
+	Name     string
+	Expected uint64
+	Got      uint64
+	Elapsed  time.Duration
+}
+
+// Lost returns how many increments disappeared
+func (r Result) Lost() uint64 {
+	if r.Got > r.Expected {
+		return 0
+	}
+	return r.Expected - r.Got
+}
+
+// OpsPerSecond returns the throughput
+func (r Result) OpsPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Expected) / r.Elapsed.Seconds()
+}
+
+// Run makes workers goroutines increment c perWorker times each
+func Run(c Counter, workers, perWorker int) Result {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < perWorker; i++ {
+				c.Inc(w)
+			}
+		}(w)
+	}
+
+	t0 := time.Now()
+	close(start)
+	wg.Wait()
+	elapsed := time.Since(t0)
+
+	return Result{
+		Name:     c.Name(),
+		Expected: uint64(workers) * uint64(perWorker),
+		Got:      c.Value(),
+		Elapsed:  elapsed,
+	}
+}
+
+// Compare runs the same workload on every counter
+func Compare(counters []Counter, workers, perWorker int) []Result {
+	var results []Result
+	for _, c := range counters {
+		results = append(results, Run(c, workers, perWorker))
+		if closer, ok := c.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+	return results
+}
+
+// PrintResults writes the results as a table
+func PrintResults(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "counter\texpected\tgot\tlost\telapsed\tops/s\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%.0f\t\n",
+			r.Name, r.Expected, r.Got, r.Lost(), r.Elapsed.Round(time.Microsecond), r.OpsPerSecond())
+	}
+	tw.Flush()
+}
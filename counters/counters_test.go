@@ -0,0 +1,86 @@
+package counters
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// unsafeRaceEnv makes TestUnsafeRace run the racy workload. It is set by
+// TestUnsafeIsReported for its child process only.
+const unsafeRaceEnv = "COUNTERS_UNSAFE_RACE"
+
+func TestSafeCounters(t *testing.T) {
+	for _, r := range Compare(All(false), 8, 10000) {
+		if r.Got != r.Expected {
+			t.Errorf("%s: got %d, want %d", r.Name, r.Got, r.Expected)
+		}
+	}
+}
+
+func TestShardedWorkers(t *testing.T) {
+	// more workers than shards, so several workers share a shard
+	c := NewSharded()
+	r := Run(c, 3*len(c.shards)+1, 1000)
+	if r.Lost() != 0 {
+		t.Errorf("lost %d increments", r.Lost())
+	}
+}
+
+func TestChannelValueDrains(t *testing.T) {
+	c := NewChannel()
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Inc(0)
+	}
+	if v := c.Value(); v != 100 {
+		t.Errorf("got %d, want 100", v)
+	}
+}
+
+func TestResult(t *testing.T) {
+	r := Result{Expected: 10, Got: 7}
+	if r.Lost() != 3 {
+		t.Errorf("lost %d, want 3", r.Lost())
+	}
+	if r.OpsPerSecond() != 0 {
+		t.Errorf("ops/s without elapsed time: %v", r.OpsPerSecond())
+	}
+}
+
+// TestUnsafeRace is the racy workload. It only runs in the child process of
+// TestUnsafeIsReported, since the race detector fails any test that races.
+func TestUnsafeRace(t *testing.T) {
+	if os.Getenv(unsafeRaceEnv) == "" {
+		t.Skip("run by TestUnsafeIsReported")
+	}
+	Run(&Unsafe{}, 4, 1000)
+}
+
+// TestUnsafeIsReported checks that the race detector reports the plain
+// increment counter
+func TestUnsafeIsReported(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the package with -race")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	cmd := exec.Command(goTool, "test", "-race", "-count=1", "-run", "^TestUnsafeRace$", ".")
+	cmd.Env = append(os.Environ(), unsafeRaceEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("race detector did not fail the unsafe counter:\n%s", out)
+	}
+	if strings.Contains(string(out), "-race is not supported") {
+		t.Skipf("race detector unavailable:\n%s", out)
+	}
+	if !strings.Contains(string(out), "WARNING: DATA RACE") {
+		t.Fatalf("no data race reported:\n%s", out)
+	}
+	if !strings.Contains(string(out), "(*Unsafe).Inc") {
+		t.Errorf("race not reported in Unsafe.Inc:\n%s", out)
+	}
+}
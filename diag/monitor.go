@@ -0,0 +1,233 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Group gathers goroutines sharing the same state and stack
+type Group struct {
+	State  string
+	IDs    []int
+	Frames []string
+	// Blocked is the longest time any member has been seen blocked
+	Blocked time.Duration
+	Stuck   bool
+}
+
+// Report summarizes one snapshot
+type Report struct {
+	Time   time.Time
+	Total  int
+	Groups []Group // largest groups first
+}
+
+// Stuck returns only the groups blocked beyond the monitor threshold
+func (r Report) Stuck() []Group {
+	var stuck []Group
+	for _, g := range r.Groups {
+		if g.Stuck {
+			stuck = append(stuck, g)
+		}
+	}
+	return stuck
+}
+
+// WriteTo prints the report in a human readable form
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "goroutine report at %s: %d goroutines in %d groups, %d stuck\n",
+		r.Time.Format("15:04:05.000"), r.Total, len(r.Groups), len(r.Stuck()))
+	for _, g := range r.Groups {
+		mark := ""
+		if g.Stuck {
+			mark = "STUCK "
+		}
+		fmt.Fprintf(&b, "\n%s%d goroutine(s) [%s]", mark, len(g.IDs), g.State)
+		if g.Blocked > 0 {
+			fmt.Fprintf(&b, " blocked for %s", g.Blocked.Round(time.Millisecond))
+		}
+		fmt.Fprintf(&b, ": %s\n", formatIDs(g.IDs))
+		for _, f := range g.Frames {
+			fmt.Fprintf(&b, "    %s\n", f)
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	r.WriteTo(&b)
+	return b.String()
+}
+
+func formatIDs(ids []int) string {
+	const max = 10
+	var parts []string
+	for i, id := range ids {
+		if i == max {
+			parts = append(parts, fmt.Sprintf("... %d more", len(ids)-max))
+			break
+		}
+		parts = append(parts, fmt.Sprint(id))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Monitor takes periodic snapshots and remembers since when each goroutine
+// sits on the same blocking operation. The runtime only reports waits longer
+// than a minute, so shorter thresholds rely on this history.
+type Monitor struct {
+	interval  time.Duration
+	threshold time.Duration
+
+	mu    sync.Mutex
+	since map[string]time.Time
+	last  Report
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewMonitor creates a monitor snapshotting every interval and flagging
+// goroutines blocked for longer than threshold
+func NewMonitor(interval, threshold time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &Monitor{
+		interval:  interval,
+		threshold: threshold,
+		since:     make(map[string]time.Time),
+	}
+}
+
+// Start runs the periodic snapshots in the background
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.loop(m.stop, m.done)
+}
+
+// Stop ends the background snapshots
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop = nil
+	m.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (m *Monitor) loop(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	m.Check()
+	for {
+		select {
+		case <-ticker.C:
+			m.Check()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Last returns the report of the latest snapshot
+func (m *Monitor) Last() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Check takes a snapshot right away and returns its report
+func (m *Monitor) Check() Report {
+	now := time.Now()
+	all := Snapshot()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups := make(map[string]*Group)
+	var order []string
+	seen := make(map[string]time.Time)
+	total := 0
+	for _, g := range all {
+		if g.isMonitor() {
+			continue
+		}
+		total++
+		sig := g.Signature()
+		grp, ok := groups[sig]
+		if !ok {
+			grp = &Group{State: g.State, Frames: g.Frames}
+			groups[sig] = grp
+			order = append(order, sig)
+		}
+		grp.IDs = append(grp.IDs, g.ID)
+		if !g.Blocked() {
+			continue
+		}
+
+		key := fmt.Sprintf("%d\n%s", g.ID, sig)
+		first, ok := m.since[key]
+		if !ok {
+			first = now
+		}
+		seen[key] = first
+		blocked := now.Sub(first)
+		if g.Wait > blocked {
+			blocked = g.Wait
+		}
+		if blocked > grp.Blocked {
+			grp.Blocked = blocked
+		}
+		if blocked >= m.threshold {
+			grp.Stuck = true
+		}
+	}
+	// forget goroutines that moved on or exited
+	m.since = seen
+
+	r := Report{Time: now, Total: total}
+	for _, sig := range order {
+		r.Groups = append(r.Groups, *groups[sig])
+	}
+	sort.SliceStable(r.Groups, func(i, j int) bool {
+		if r.Groups[i].Stuck != r.Groups[j].Stuck {
+			return r.Groups[i].Stuck
+		}
+		return len(r.Groups[i].IDs) > len(r.Groups[j].IDs)
+	})
+	m.last = r
+	return r
+}
+
+// pkgPrefix is used to hide the monitor's own goroutines from reports
+var pkgPrefix = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(NewMonitor).Pointer()).Name()
+	return strings.TrimSuffix(name, "NewMonitor")
+}()
+
+func (g Goroutine) isMonitor() bool {
+	for _, f := range g.Frames {
+		if strings.HasPrefix(f, pkgPrefix) {
+			return true
+		}
+	}
+	return false
+}
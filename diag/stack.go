@@ -0,0 +1,125 @@
+// Package diag inspects the goroutines of the running program to find the
+// ones stuck on channel or lock operations.
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Goroutine is one entry of a runtime.Stack dump
+type Goroutine struct {
+	ID     int
+	State  string        // e.g. "chan receive", "select", "sleep"
+	Wait   time.Duration // wait time reported by the runtime (minute resolution)
+	Frames []string      // function and file:line pairs, without arguments
+}
+
+// Signature identifies goroutines with the same state and stack
+func (g Goroutine) Signature() string {
+	return g.State + "\n" + strings.Join(g.Frames, "\n")
+}
+
+// Blocked tells if the goroutine waits on a channel or a lock. Sleeping and
+// I/O waits are not considered blocked.
+func (g Goroutine) Blocked() bool {
+	for _, prefix := range blockingStates {
+		if strings.HasPrefix(g.State, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var blockingStates = []string{
+	"chan receive",
+	"chan send",
+	"select",
+	"semacquire",
+	"sync.Mutex.Lock",
+	"sync.RWMutex.Lock",
+	"sync.RWMutex.RLock",
+	"sync.Cond.Wait",
+	"sync.WaitGroup.Wait",
+}
+
+var (
+	headerRe  = regexp.MustCompile(`^goroutine (\d+) \[([^\]]*)\]:$`)
+	argsRe    = regexp.MustCompile(`\([^()]*\)$`) // the arguments, not the receiver
+	offsetRe  = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+	creatorRe = regexp.MustCompile(` in goroutine \d+$`)
+	minutesRe = regexp.MustCompile(`^(\d+) minutes?$`)
+)
+
+// Snapshot returns every goroutine of the program
+func Snapshot() []Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return Parse(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Parse reads the text produced by runtime.Stack
+func Parse(dump []byte) []Goroutine {
+	var list []Goroutine
+	var cur *Goroutine
+	sc := bufio.NewScanner(bytes.NewReader(dump))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if m := headerRe.FindStringSubmatch(line); m != nil {
+			list = append(list, parseHeader(m[1], m[2]))
+			cur = &list[len(list)-1]
+			continue
+		}
+		if cur == nil || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			line = "\t" + offsetRe.ReplaceAllString(strings.TrimSpace(line), "")
+		} else {
+			line = creatorRe.ReplaceAllString(line, "")
+			if !strings.HasPrefix(line, "created by ") {
+				line = argsRe.ReplaceAllString(line, "")
+			}
+		}
+		cur.Frames = append(cur.Frames, line)
+	}
+	return list
+}
+
+func parseHeader(id, status string) Goroutine {
+	g := Goroutine{}
+	g.ID, _ = strconv.Atoi(id)
+	parts := strings.Split(status, ", ")
+	g.State = parts[0]
+	for _, p := range parts[1:] {
+		if m := minutesRe.FindStringSubmatch(p); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			g.Wait = time.Duration(n) * time.Minute
+		}
+	}
+	return g
+}
+
+// TopFunction returns the first function of the stack outside the runtime,
+// which is usually the line doing the blocking operation
+func (g Goroutine) TopFunction() string {
+	for _, f := range g.Frames {
+		if strings.HasPrefix(f, "\t") || strings.HasPrefix(f, "runtime.") ||
+			strings.HasPrefix(f, "sync.") || strings.HasPrefix(f, "internal/") {
+			continue
+		}
+		return f
+	}
+	return ""
+}
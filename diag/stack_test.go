@@ -0,0 +1,104 @@
+package diag
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden returns the expected content of name, first writing got to it with
+// -update
+func golden(t *testing.T, name string, got []byte) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	return want
+}
+
+// describe renders what Parse found, one goroutine per paragraph
+func describe(list []Goroutine) []byte {
+	var b bytes.Buffer
+	for _, g := range list {
+		fmt.Fprintf(&b, "goroutine %d state=%q wait=%v blocked=%v top=%q\n", g.ID, g.State, g.Wait, g.Blocked(), g.TopFunction())
+		for _, f := range g.Frames {
+			fmt.Fprintf(&b, "  %s\n", f)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+func TestParseGolden(t *testing.T) {
+	dumps, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dump := range dumps {
+		data, err := os.ReadFile(dump)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := describe(Parse(data))
+		name := strings.TrimSuffix(filepath.Base(dump), ".txt") + ".golden"
+		if want := golden(t, name, got); !bytes.Equal(got, want) {
+			t.Errorf("%s differs from testdata/%s:\n%s", dump, name, got)
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	dump := []byte(`goroutine 5 [chan receive]:
+main.worker(0xc000012345)
+	/app/worker.go:18 +0x4b
+
+goroutine 6 [chan receive]:
+main.worker(0xc000099999)
+	/app/worker.go:18 +0x4b
+
+goroutine 7 [chan receive]:
+main.worker(0xc000012345)
+	/app/worker.go:19 +0x4b
+`)
+	list := Parse(dump)
+	if len(list) != 3 {
+		t.Fatalf("got %d goroutines, want 3", len(list))
+	}
+	// arguments and offsets don't matter, lines do
+	if list[0].Signature() != list[1].Signature() {
+		t.Errorf("signatures differ:\n%s\n%s", list[0].Signature(), list[1].Signature())
+	}
+	if list[0].Signature() == list[2].Signature() {
+		t.Error("goroutines on different lines have the same signature")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	go func() { <-block }()
+	// give the goroutine time to start and block
+	for i := 0; i < 100; i++ {
+		for _, g := range Snapshot() {
+			if g.State == "chan receive" && strings.Contains(g.TopFunction(), "TestSnapshot") {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("blocked goroutine not found")
+}
@@ -0,0 +1,178 @@
+// Package errs provides a structured error with a code, a message, an
+// optional cause and key/value fields. Errors match with errors.Is by code,
+// so callers can branch on the kind of error instead of parsing its text.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code tells the kind of an error
+type Code int
+
+// Catalog of codes. Values up to 99 are reserved for this list; programs
+// may use their own codes above that, or any code they like if they don't
+// mix with others.
+const (
+	Unknown Code = iota
+	Internal
+	InvalidArgument
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	Unauthenticated
+	ResourceExhausted
+	FailedPrecondition
+	Unavailable
+	DeadlineExceeded
+	Canceled
+	Unimplemented
+	// DontPanic is the answer to the ultimate question, used by the examples
+	DontPanic Code = 42
+)
+
+var codeNames = map[Code]string{
+	Unknown:            "unknown",
+	Internal:           "internal",
+	InvalidArgument:    "invalid_argument",
+	NotFound:           "not_found",
+	AlreadyExists:      "already_exists",
+	PermissionDenied:   "permission_denied",
+	Unauthenticated:    "unauthenticated",
+	ResourceExhausted:  "resource_exhausted",
+	FailedPrecondition: "failed_precondition",
+	Unavailable:        "unavailable",
+	DeadlineExceeded:   "deadline_exceeded",
+	Canceled:           "canceled",
+	Unimplemented:      "unimplemented",
+	DontPanic:          "dont_panic",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("code_%d", int(c))
+}
+
+// Sentinels to use as errors.Is targets. Only the code is compared.
+var (
+	ErrInternal           = &Error{Code: Internal, Message: "internal error"}
+	ErrInvalidArgument    = &Error{Code: InvalidArgument, Message: "invalid argument"}
+	ErrNotFound           = &Error{Code: NotFound, Message: "not found"}
+	ErrAlreadyExists      = &Error{Code: AlreadyExists, Message: "already exists"}
+	ErrPermissionDenied   = &Error{Code: PermissionDenied, Message: "permission denied"}
+	ErrUnauthenticated    = &Error{Code: Unauthenticated, Message: "unauthenticated"}
+	ErrResourceExhausted  = &Error{Code: ResourceExhausted, Message: "resource exhausted"}
+	ErrFailedPrecondition = &Error{Code: FailedPrecondition, Message: "failed precondition"}
+	ErrUnavailable        = &Error{Code: Unavailable, Message: "unavailable"}
+	ErrDeadlineExceeded   = &Error{Code: DeadlineExceeded, Message: "deadline exceeded"}
+	ErrCanceled           = &Error{Code: Canceled, Message: "canceled"}
+	ErrUnimplemented      = &Error{Code: Unimplemented, Message: "unimplemented"}
+	ErrDontPanic          = &Error{Code: DontPanic, Message: "don't panic"}
+)
+
+// Field is a key/value pair giving context to an error
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Error is a structured error
+type Error struct {
+	Code    Code
+	Message string
+	// Key looks up the message in the catalogs instead, with Fields as
+	// parameters
+	Key    string
+	Cause  error
+	Fields []Field
+
+	stack []uintptr
+}
+
+// New creates an error recording the stack of the caller. kv are
+// alternating keys and values, like "user", 42, "path", "/tmp".
+func New(code Code, message string, kv ...any) *Error {
+	return &Error{Code: code, Message: message, Fields: fields(kv), stack: callers()}
+}
+
+// Wrap creates an error caused by cause. A nil cause gives a nil error,
+// which is why the result is not an *Error. The stack is recorded only if
+// cause has none, so %+v shows where the problem started.
+func Wrap(cause error, code Code, message string, kv ...any) error {
+	if cause == nil {
+		return nil
+	}
+	e := &Error{Code: code, Message: message, Cause: cause, Fields: fields(kv)}
+	if !hasStack(cause) {
+		e.stack = callers()
+	}
+	return e
+}
+
+func fields(kv []any) []Field {
+	var list []Field
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		var val any = "(missing)"
+		if i+1 < len(kv) {
+			val = kv[i+1]
+		}
+		list = append(list, Field{key, val})
+	}
+	return list
+}
+
+// With returns a copy of e with more fields
+func (e *Error) With(kv ...any) *Error {
+	c := *e
+	c.Fields = append(append([]Field(nil), e.Fields...), fields(kv)...)
+	return &c
+}
+
+// Error renders "code - message key=value: cause". Messages with a key are
+// translated to the language set with SetLocale and their fields only fill
+// the placeholders.
+func (e *Error) Error() string {
+	return e.Localize(Locale())
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is an *Error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Field returns the value of the first field named key, looking through the
+// whole chain of causes
+func (e *Error) Field(key string) (any, bool) {
+	var err error = e
+	for err != nil {
+		if se, ok := err.(*Error); ok {
+			for _, f := range se.Fields {
+				if f.Key == key {
+					return f.Value, true
+				}
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return nil, false
+}
+
+// CodeOf returns the code of the first *Error in the chain of err, Unknown
+// if there is none and err is not nil
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Unknown
+}
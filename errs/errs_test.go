@@ -0,0 +1,108 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestIs(t *testing.T) {
+	notFound := New(NotFound, "user missing", "user", 7)
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"same code", notFound, ErrNotFound, true},
+		{"other message", New(NotFound, "order missing"), ErrNotFound, true},
+		{"other code", notFound, ErrInternal, false},
+		{"wrapped with fmt", fmt.Errorf("loading: %w", notFound), ErrNotFound, true},
+		{"cause", Wrap(notFound, Internal, "lookup failed"), ErrNotFound, true},
+		{"outer code", Wrap(notFound, Internal, "lookup failed"), ErrInternal, true},
+		{"cause not an *Error", Wrap(io.EOF, Internal, "read failed"), io.EOF, true},
+		{"plain error", io.EOF, ErrNotFound, false},
+		{"target not an *Error", notFound, io.EOF, false},
+		{"custom code", New(1000, "custom"), &Error{Code: 1000}, true},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	inner := New(NotFound, "user missing", "user", 7, "shard", "b")
+	outer := Wrap(fmt.Errorf("query: %w", inner), Internal, "lookup failed", "user", 8).(*Error)
+	tests := []struct {
+		key   string
+		want  any
+		found bool
+	}{
+		{"user", 8, true}, // the outer error comes first
+		{"shard", "b", true},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		got, found := outer.Field(tt.key)
+		if got != tt.want || found != tt.found {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.key, got, found, tt.want, tt.found)
+		}
+	}
+
+	odd := New(InvalidArgument, "bad", "name")
+	if got, _ := odd.Field("name"); got != "(missing)" {
+		t.Errorf("odd fields: got %v, want (missing)", got)
+	}
+
+	with := inner.With("retry", true)
+	if _, ok := inner.Field("retry"); ok {
+		t.Error("With changed the original error")
+	}
+	if got, _ := with.Field("retry"); got != true {
+		t.Errorf("With: got %v, want true", got)
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"message", New(NotFound, "user missing"), "3 - user missing"},
+		{"fields", New(NotFound, "user missing", "user", 7, "shard", "b"), "3 - user missing user=7 shard=b"},
+		{"cause", Wrap(io.EOF, Internal, "read failed"), "1 - read failed: EOF"},
+		{"custom code", New(1000, "custom"), "1000 - custom"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Code
+	}{
+		{New(NotFound, "x"), NotFound},
+		{fmt.Errorf("a: %w", Wrap(New(NotFound, "x"), Unavailable, "y")), Unavailable},
+		{io.EOF, Unknown},
+		{nil, Unknown},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if Wrap(nil, Internal, "nothing") != nil {
+		t.Error("Wrap(nil) is not nil")
+	}
+	if got := Code(1000).String(); got != "code_1000" {
+		t.Errorf("got %q, want code_1000", got)
+	}
+}
@@ -0,0 +1,186 @@
+package errs
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// FallbackLocale is used for keys missing in the requested language
+const FallbackLocale = "en"
+
+var (
+	catalogMu sync.RWMutex
+	catalogs  = make(map[string]map[string]string)
+	locale    = FallbackLocale
+)
+
+func init() {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("errs: %s: %v", entry.Name(), err))
+		}
+		AddMessages(strings.TrimSuffix(entry.Name(), ".json"), messages)
+	}
+}
+
+// AddMessages adds or replaces messages of a language
+func AddMessages(lang string, messages map[string]string) {
+	lang = normalize(lang)
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if catalogs[lang] == nil {
+		catalogs[lang] = make(map[string]string)
+	}
+	for k, v := range messages {
+		catalogs[lang][k] = v
+	}
+}
+
+// SetLocale sets the language used by Error()
+func SetLocale(lang string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	locale = normalize(lang)
+}
+
+// Locale returns the language used by Error()
+func Locale() string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return locale
+}
+
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
+
+// chain lists the languages to try: "pt-br" gives pt-br, pt, en
+func chain(lang string) []string {
+	var list []string
+	for lang = normalize(lang); lang != ""; {
+		list = append(list, lang)
+		i := strings.LastIndex(lang, "-")
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return append(list, FallbackLocale)
+}
+
+// Translate returns the message of key in lang, filling {name}
+// placeholders from params. If no language in the fallback chain knows the
+// key, the key itself is returned.
+func Translate(lang, key string, params []Field) string {
+	catalogMu.RLock()
+	msg, found := key, false
+	for _, l := range chain(lang) {
+		if m, ok := catalogs[l][key]; ok {
+			msg, found = m, true
+			break
+		}
+	}
+	catalogMu.RUnlock()
+	if !found || len(params) == 0 {
+		return msg
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for _, p := range params {
+		pairs = append(pairs, "{"+p.Key+"}", fmt.Sprint(p.Value))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// NewKey creates an error whose message is looked up in the catalogs by
+// key, using the fields as parameters
+func NewKey(code Code, key string, kv ...any) *Error {
+	return &Error{Code: code, Key: key, Fields: fields(kv), stack: callers()}
+}
+
+// Localize renders e like Error() but in lang. Causes that are *Error are
+// translated too.
+func (e *Error) Localize(lang string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d - %s", int(e.Code), e.message(lang))
+	if e.Key == "" {
+		for _, f := range e.Fields {
+			fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+		}
+	}
+	if e.Cause != nil {
+		b.WriteString(": ")
+		b.WriteString(Localize(e.Cause, lang))
+	}
+	return b.String()
+}
+
+// message is the translated message, or Message if e has no key
+func (e *Error) message(lang string) string {
+	if e.Key == "" {
+		return e.Message
+	}
+	return Translate(lang, e.Key, e.Fields)
+}
+
+// Localize renders err in lang. Every *Error in the chain is translated;
+// the text the other errors add around their cause, like the prefix of
+// fmt.Errorf("loading: %w", err), is kept.
+func Localize(err error, lang string) string {
+	if e, ok := err.(*Error); ok {
+		return e.Localize(lang)
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+	text := err.Error()
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		cause := u.Unwrap()
+		if cause == nil {
+			return text
+		}
+		inner := cause.Error()
+		switch {
+		case strings.HasSuffix(text, inner):
+			return text[:len(text)-len(inner)] + Localize(cause, lang)
+		case strings.HasPrefix(text, inner):
+			return Localize(cause, lang) + text[len(inner):]
+		}
+		// the wrapper doesn't show the message of its cause, so there's
+		// no place for the translation
+		return Localize(cause, lang)
+	case interface{ Unwrap() []error }:
+		// errors.Join puts each error on its own line
+		members := u.Unwrap()
+		lines := make([]string, len(members))
+		for i, m := range members {
+			lines[i] = m.Error()
+		}
+		if text != strings.Join(lines, "\n") {
+			return text
+		}
+		for i, m := range members {
+			lines[i] = Localize(m, lang)
+		}
+		return strings.Join(lines, "\n")
+	}
+	return text
+}
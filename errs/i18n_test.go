@@ -0,0 +1,44 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	notFound := NewKey(NotFound, "planet_not_found", "name", "Vulcan")
+	tests := []struct {
+		name string
+		err  error
+		lang string
+		want string
+	}{
+		{"error", notFound, "pt", "3 - planeta Vulcan não encontrado"},
+		{"region falls back to language", notFound, "pt_BR", "3 - planeta Vulcan não encontrado"},
+		{"unknown language falls back to en", notFound, "de", "3 - planet Vulcan not found"},
+		{"wrapped with fmt", fmt.Errorf("loading map: %w", notFound), "it", "loading map: 3 - pianeta Vulcan non trovato"},
+		{"wrapped twice", fmt.Errorf("request: %w", fmt.Errorf("loading map: %w", notFound)), "pt", "request: loading map: 3 - planeta Vulcan não encontrado"},
+		{"cause translated", Wrap(notFound, Internal, "lookup failed"), "pt", "1 - lookup failed: 3 - planeta Vulcan não encontrado"},
+		{"wrapper hiding the message", hidden{notFound}, "pt", "3 - planeta Vulcan não encontrado"},
+		{"message repeated in the prefix", fmt.Errorf("%s: %w", notFound.Error(), notFound), "pt", "3 - planet Vulcan not found: 3 - planeta Vulcan não encontrado"},
+		{"wrapped as a prefix", fmt.Errorf("%w (retrying)", notFound), "it", "3 - pianeta Vulcan non trovato (retrying)"},
+		{"cause of a wrapper", fmt.Errorf("loading map: %w", Wrap(notFound, Internal, "lookup failed")), "pt", "loading map: 1 - lookup failed: 3 - planeta Vulcan não encontrado"},
+		{"joined", errors.Join(errors.New("disk full"), notFound), "pt", "disk full\n3 - planeta Vulcan não encontrado"},
+		{"plain error", errors.New("disk full"), "pt", "disk full"},
+		{"missing key", NewKey(Internal, "no_such_key"), "pt", "1 - no_such_key"},
+	}
+	for _, tt := range tests {
+		if got := Localize(tt.err, tt.lang); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// hidden wraps an error without showing its message
+type hidden struct {
+	err error
+}
+
+func (h hidden) Error() string { return "something went wrong" }
+func (h hidden) Unwrap() error { return h.err }
@@ -0,0 +1,133 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// GRPCCode is a canonical status code as used by gRPC
+type GRPCCode int
+
+// Canonical codes, with the values gRPC uses on the wire
+const (
+	GRPCOK GRPCCode = iota
+	GRPCCanceled
+	GRPCUnknown
+	GRPCInvalidArgument
+	GRPCDeadlineExceeded
+	GRPCNotFound
+	GRPCAlreadyExists
+	GRPCPermissionDenied
+	GRPCResourceExhausted
+	GRPCFailedPrecondition
+	GRPCAborted
+	GRPCOutOfRange
+	GRPCUnimplemented
+	GRPCInternal
+	GRPCUnavailable
+	GRPCDataLoss
+	GRPCUnauthenticated
+)
+
+var grpcNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+func (c GRPCCode) String() string {
+	if c >= 0 && int(c) < len(grpcNames) {
+		return grpcNames[c]
+	}
+	return fmt.Sprintf("GRPC_CODE_%d", int(c))
+}
+
+// Mapping tells how an error code is reported outside the program
+type Mapping struct {
+	HTTPStatus int
+	ExitCode   int
+	GRPC       GRPCCode
+	// Type is the problem type URI, "about:blank" if empty
+	Type string
+}
+
+// Registry maps error codes to their external representations
+type Registry struct {
+	mu       sync.RWMutex
+	mappings map[Code]Mapping
+	fallback Mapping
+}
+
+// NewRegistry creates a registry knowing the codes of the catalog. Exit
+// codes follow sysexits.h.
+func NewRegistry() *Registry {
+	return &Registry{
+		mappings: map[Code]Mapping{
+			Internal:           {HTTPStatus: 500, ExitCode: 70, GRPC: GRPCInternal},
+			InvalidArgument:    {HTTPStatus: 400, ExitCode: 64, GRPC: GRPCInvalidArgument},
+			NotFound:           {HTTPStatus: 404, ExitCode: 66, GRPC: GRPCNotFound},
+			AlreadyExists:      {HTTPStatus: 409, ExitCode: 73, GRPC: GRPCAlreadyExists},
+			PermissionDenied:   {HTTPStatus: 403, ExitCode: 77, GRPC: GRPCPermissionDenied},
+			Unauthenticated:    {HTTPStatus: 401, ExitCode: 77, GRPC: GRPCUnauthenticated},
+			ResourceExhausted:  {HTTPStatus: 429, ExitCode: 75, GRPC: GRPCResourceExhausted},
+			FailedPrecondition: {HTTPStatus: 412, ExitCode: 65, GRPC: GRPCFailedPrecondition},
+			Unavailable:        {HTTPStatus: 503, ExitCode: 69, GRPC: GRPCUnavailable},
+			DeadlineExceeded:   {HTTPStatus: 504, ExitCode: 75, GRPC: GRPCDeadlineExceeded},
+			Canceled:           {HTTPStatus: 499, ExitCode: 130, GRPC: GRPCCanceled},
+			Unimplemented:      {HTTPStatus: 501, ExitCode: 70, GRPC: GRPCUnimplemented},
+			DontPanic:          {HTTPStatus: 418, ExitCode: 42, GRPC: GRPCAborted},
+		},
+		fallback: Mapping{HTTPStatus: 500, ExitCode: 1, GRPC: GRPCUnknown},
+	}
+}
+
+// DefaultRegistry is used by the package level functions
+var DefaultRegistry = NewRegistry()
+
+// Register sets the mapping of code, replacing any previous one
+func (r *Registry) Register(code Code, m Mapping) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mappings[code] = m
+}
+
+// Lookup returns the mapping of err. A nil error maps to success, and
+// context errors map like Canceled and DeadlineExceeded.
+func (r *Registry) Lookup(err error) Mapping {
+	if err == nil {
+		return Mapping{HTTPStatus: 200, ExitCode: 0, GRPC: GRPCOK}
+	}
+	code := CodeOf(err)
+	if code == Unknown {
+		switch {
+		case errors.Is(err, context.Canceled):
+			code = Canceled
+		case errors.Is(err, context.DeadlineExceeded):
+			code = DeadlineExceeded
+		}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if m, ok := r.mappings[code]; ok {
+		return m
+	}
+	return r.fallback
+}
+
+// HTTPStatus returns the HTTP status of err using DefaultRegistry
+func HTTPStatus(err error) int {
+	return DefaultRegistry.Lookup(err).HTTPStatus
+}
+
+// ExitCode returns the process exit code of err using DefaultRegistry
+func ExitCode(err error) int {
+	return DefaultRegistry.Lookup(err).ExitCode
+}
+
+// GRPCStatus returns the canonical code of err using DefaultRegistry
+func GRPCStatus(err error) GRPCCode {
+	return DefaultRegistry.Lookup(err).GRPC
+}
@@ -0,0 +1,69 @@
+package errs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Mapping
+	}{
+		{"nil", nil, Mapping{HTTPStatus: 200, ExitCode: 0, GRPC: GRPCOK}},
+		{"not found", New(NotFound, "x"), Mapping{HTTPStatus: 404, ExitCode: 66, GRPC: GRPCNotFound}},
+		{"invalid argument", New(InvalidArgument, "x"), Mapping{HTTPStatus: 400, ExitCode: 64, GRPC: GRPCInvalidArgument}},
+		{"unavailable", New(Unavailable, "x"), Mapping{HTTPStatus: 503, ExitCode: 69, GRPC: GRPCUnavailable}},
+		{"outer code wins", Wrap(New(NotFound, "x"), PermissionDenied, "y"), Mapping{HTTPStatus: 403, ExitCode: 77, GRPC: GRPCPermissionDenied}},
+		{"wrapped with fmt", fmt.Errorf("a: %w", New(Internal, "x")), Mapping{HTTPStatus: 500, ExitCode: 70, GRPC: GRPCInternal}},
+		{"context canceled", fmt.Errorf("a: %w", context.Canceled), Mapping{HTTPStatus: 499, ExitCode: 130, GRPC: GRPCCanceled}},
+		{"context deadline", context.DeadlineExceeded, Mapping{HTTPStatus: 504, ExitCode: 75, GRPC: GRPCDeadlineExceeded}},
+		{"plain error", io.EOF, Mapping{HTTPStatus: 500, ExitCode: 1, GRPC: GRPCUnknown}},
+		{"unregistered code", New(1000, "x"), Mapping{HTTPStatus: 500, ExitCode: 1, GRPC: GRPCUnknown}},
+	}
+	r := NewRegistry()
+	for _, tt := range tests {
+		if got := r.Lookup(tt.err); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	quota := Mapping{HTTPStatus: 402, ExitCode: 3, GRPC: GRPCResourceExhausted, Type: "https://example.com/quota"}
+	r.Register(1000, quota)
+	r.Register(NotFound, Mapping{HTTPStatus: 410, ExitCode: 66, GRPC: GRPCNotFound})
+	if got := r.Lookup(New(1000, "x")); got != quota {
+		t.Errorf("new code: got %+v", got)
+	}
+	if got := r.Lookup(New(NotFound, "x")).HTTPStatus; got != 410 {
+		t.Errorf("replaced code: got %d, want 410", got)
+	}
+	// registries don't share their mappings
+	if got := HTTPStatus(New(NotFound, "x")); got != 404 {
+		t.Errorf("default registry: got %d, want 404", got)
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	err := New(ResourceExhausted, "x")
+	if got := HTTPStatus(err); got != 429 {
+		t.Errorf("HTTPStatus: got %d, want 429", got)
+	}
+	if got := ExitCode(err); got != 75 {
+		t.Errorf("ExitCode: got %d, want 75", got)
+	}
+	if got := GRPCStatus(err); got != GRPCResourceExhausted {
+		t.Errorf("GRPCStatus: got %v, want %v", got, GRPCResourceExhausted)
+	}
+	if got := GRPCCode(99).String(); got != "GRPC_CODE_99" {
+		t.Errorf("got %q", got)
+	}
+	if got := GRPCCanceled.String(); got != "CANCELLED" {
+		t.Errorf("got %q", got)
+	}
+}
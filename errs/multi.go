@@ -0,0 +1,147 @@
+package errs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MultiError collects the errors of a batch of operations. It is safe for
+// concurrent use and the zero value is ready to use. Identical errors, same
+// type and message, are kept once and counted.
+type MultiError struct {
+	mu     sync.Mutex
+	errs   []error
+	counts []int
+	index  map[string]int
+}
+
+// Add records err. Nil errors are ignored and errors made with errors.Join
+// or another MultiError are added member by member.
+func (m *MultiError) Add(err error) {
+	m.add(err, 1)
+}
+
+func (m *MultiError) add(err error, n int) {
+	if err == nil {
+		return
+	}
+	if other, ok := err.(*MultiError); ok {
+		other.mu.Lock()
+		errs, counts := append([]error(nil), other.errs...), append([]int(nil), other.counts...)
+		other.mu.Unlock()
+		for i, e := range errs {
+			m.add(e, n*counts[i])
+		}
+		return
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range j.Unwrap() {
+			m.add(e, n)
+		}
+		return
+	}
+	key := fmt.Sprintf("%T\x00%s", err, err.Error())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.index == nil {
+		m.index = make(map[string]int)
+	}
+	if i, ok := m.index[key]; ok {
+		m.counts[i] += n
+		return
+	}
+	m.index[key] = len(m.errs)
+	m.errs = append(m.errs, err)
+	m.counts = append(m.counts, n)
+}
+
+// Len returns the number of distinct errors
+func (m *MultiError) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.errs)
+}
+
+// Errors returns the distinct errors in the order they were first added
+func (m *MultiError) Errors() []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]error(nil), m.errs...)
+}
+
+// Err returns nil if nothing failed, or a copy of m that later Adds don't
+// change. Return this rather than m itself, which is never a nil error.
+func (m *MultiError) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.errs) == 0 {
+		return nil
+	}
+	c := &MultiError{
+		errs:   append([]error(nil), m.errs...),
+		counts: append([]int(nil), m.counts...),
+		index:  make(map[string]int, len(m.index)),
+	}
+	for k, v := range m.index {
+		c.index[k] = v
+	}
+	return c
+}
+
+// Unwrap lets errors.Is and errors.As look at every member
+func (m *MultiError) Unwrap() []error {
+	return m.Errors()
+}
+
+// Error is the compact summary, one line
+func (m *MultiError) Error() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parts := make([]string, len(m.errs))
+	for i, err := range m.errs {
+		parts[i] = err.Error()
+		if m.counts[i] > 1 {
+			parts[i] += fmt.Sprintf(" (x%d)", m.counts[i])
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "no errors"
+	case 1:
+		return parts[0]
+	}
+	return fmt.Sprintf("%d errors: %s", len(parts), strings.Join(parts, "; "))
+}
+
+// Detailed lists each error on its own line, with its stack if it has one
+func (m *MultiError) Detailed() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d distinct errors", len(m.errs))
+	for i, err := range m.errs {
+		fmt.Fprintf(&b, "\n[%d] x%d: ", i+1, m.counts[i])
+		b.WriteString(strings.ReplaceAll(fmt.Sprintf("%+v", err), "\n", "\n    "))
+	}
+	return b.String()
+}
+
+// Format prints the detailed summary with %+v
+func (m *MultiError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		io.WriteString(s, m.Detailed())
+		return
+	}
+	format(m, s, verb)
+}
+
+// Join is errors.Join without duplicates
+func Join(errs ...error) error {
+	var m MultiError
+	for _, err := range errs {
+		m.Add(err)
+	}
+	return m.Err()
+}
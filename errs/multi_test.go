@@ -0,0 +1,123 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMultiError(t *testing.T) {
+	var nested MultiError
+	nested.Add(io.EOF)
+	nested.Add(io.EOF)
+	tests := []struct {
+		name string
+		errs []error
+		want string
+		len  int
+	}{
+		{"none", nil, "no errors", 0},
+		{"nil ignored", []error{nil, nil}, "no errors", 0},
+		{"one", []error{io.EOF}, "EOF", 1},
+		{"duplicates counted", []error{io.EOF, io.EOF, io.EOF}, "EOF (x3)", 1},
+		{"first seen order", []error{io.EOF, io.ErrClosedPipe, io.EOF}, "2 errors: EOF (x2); io: read/write on closed pipe", 2},
+		{"same text, other type", []error{textError("EOF"), io.EOF}, "2 errors: EOF; EOF", 2},
+		{"equal *Error", []error{New(NotFound, "x"), New(NotFound, "x")}, "3 - x (x2)", 1},
+		{"joined", []error{errors.Join(io.EOF, io.EOF), io.EOF}, "EOF (x3)", 1},
+		{"nested keeps counts", []error{&nested, io.EOF}, "EOF (x3)", 1},
+	}
+	for _, tt := range tests {
+		var m MultiError
+		for _, err := range tt.errs {
+			m.Add(err)
+		}
+		if got := m.Error(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if m.Len() != tt.len {
+			t.Errorf("%s: Len %d, want %d", tt.name, m.Len(), tt.len)
+		}
+		if (m.Err() == nil) != (tt.len == 0) {
+			t.Errorf("%s: Err %v", tt.name, m.Err())
+		}
+	}
+}
+
+// textError has the message of io.EOF but another type
+type textError string
+
+func (e textError) Error() string { return string(e) }
+
+func TestMultiErrorConcurrentAdd(t *testing.T) {
+	var m MultiError
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Add(fmt.Errorf("worker %d", i%4))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if m.Len() != 4 {
+		t.Fatalf("got %d distinct errors, want 4", m.Len())
+	}
+	for i := 0; i < 4; i++ {
+		if want := fmt.Sprintf("worker %d (x200)", i); !strings.Contains(m.Error(), want) {
+			t.Errorf("%q missing in %q", want, m.Error())
+		}
+	}
+}
+
+func TestMultiErrorErr(t *testing.T) {
+	var m MultiError
+	m.Add(New(NotFound, "x"))
+	err := m.Err()
+	m.Add(io.EOF)
+	if err.Error() != "3 - x" {
+		t.Errorf("later Add changed the result of Err: %q", err)
+	}
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, io.EOF) {
+		t.Errorf("errors.Is does not see the members of %v", err)
+	}
+	var e *Error
+	if !errors.As(&m, &e) || e.Code != NotFound {
+		t.Errorf("errors.As: got %v", e)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if err := Join(nil, nil); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	if got := Join(io.EOF, nil, io.EOF).Error(); got != "EOF (x2)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMultiErrorDetailed(t *testing.T) {
+	var m MultiError
+	m.Add(newHere())
+	m.Add(io.EOF)
+	m.Add(io.EOF)
+	got := fmt.Sprintf("%+v", &m)
+	lines := strings.Split(got, "\n")
+	if lines[0] != "2 distinct errors" || lines[1] != "[1] x1: 1 - here" {
+		t.Errorf("got\n%s", got)
+	}
+	// the stack of a member is indented under it
+	if len(lines) < 3 || !strings.HasPrefix(lines[2], "    ") || !strings.HasSuffix(lines[2], ".newHere") {
+		t.Errorf("no stack for the first error:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "\n[2] x2: EOF") {
+		t.Errorf("got\n%s", got)
+	}
+	if s := fmt.Sprintf("%v", &m); s != m.Error() {
+		t.Errorf("%%v: got %q", s)
+	}
+}
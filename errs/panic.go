@@ -0,0 +1,118 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"bitbucket.org/feliposz/go-by-example/group"
+)
+
+// PanicOption configures how panics are recovered
+type PanicOption func(*panicOptions)
+
+type panicOptions struct {
+	repanicRuntime bool
+}
+
+// RepanicRuntimeErrors lets runtime errors, like a nil dereference or an
+// index out of range, crash the program as usual. They are bugs rather than
+// failures to handle.
+func RepanicRuntimeErrors() PanicOption {
+	return func(o *panicOptions) {
+		o.repanicRuntime = true
+	}
+}
+
+// recovered turns the value of recover() into a *group.PanicError, or
+// panics again if the options say so
+func recovered(r any, opts []PanicOption) error {
+	var o panicOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if _, ok := r.(runtime.Error); ok && o.repanicRuntime {
+		panic(r)
+	}
+	return &group.PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// SafeCall calls fn and returns its error, or a *group.PanicError if it
+// panicked
+func SafeCall(fn func() error, opts ...PanicOption) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(r, opts)
+		}
+	}()
+	return fn()
+}
+
+// SafeGo runs fn in a new goroutine. If it panics, onPanic receives the
+// *group.PanicError instead of the program crashing.
+func SafeGo(fn func(), onPanic func(error), opts ...PanicOption) {
+	go func() {
+		err := SafeCall(func() error {
+			fn()
+			return nil
+		}, opts...)
+		if err != nil && onPanic != nil {
+			onPanic(err)
+		}
+	}()
+}
+
+// Recover is a middleware answering 500 with a problem details body when
+// next panics. http.ErrAbortHandler is let through, as net/http expects. If
+// next had already started the response, or the problem can't be written,
+// the response is aborted with http.ErrAbortHandler so the client doesn't
+// mistake it for a complete one.
+func Recover(next http.Handler, opts ...PanicOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w}
+		err := SafeCall(func() error {
+			next.ServeHTTP(tw, req)
+			return nil
+		}, opts...)
+		var perr *group.PanicError
+		if !errors.As(err, &perr) {
+			return
+		}
+		if perr.Value == http.ErrAbortHandler || tw.wrote {
+			panic(http.ErrAbortHandler)
+		}
+		if WriteProblem(w, req, Wrap(err, Internal, "handler panicked", "path", req.URL.Path)) != nil {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// trackingWriter remembers whether the handler started the response
+type trackingWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *trackingWriter) WriteHeader(code int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response, if the underlying writer can
+func (w *trackingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
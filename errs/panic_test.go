@@ -0,0 +1,123 @@
+package errs
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitbucket.org/feliposz/go-by-example/group"
+)
+
+// serve calls h with a request for /orders/7 and returns the value it
+// panicked with, if any
+func serve(h http.Handler, w http.ResponseWriter) (rec any) {
+	defer func() {
+		rec = recover()
+	}()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/orders/7", nil))
+	return nil
+}
+
+func TestRecoverWritesProblem(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+	w := httptest.NewRecorder()
+	if r := serve(h, w); r != nil {
+		t.Fatalf("panicked with %v", r)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("content type %q", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"status":500`) || strings.Contains(body, "boom") {
+		t.Errorf("body %s", body)
+	}
+}
+
+func TestRecoverAfterWrite(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"header": func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic("boom")
+		},
+		"body": func(w http.ResponseWriter, req *http.Request) {
+			io.WriteString(w, "partial")
+			panic("boom")
+		},
+		"flush": func(w http.ResponseWriter, req *http.Request) {
+			w.(http.Flusher).Flush()
+			panic("boom")
+		},
+	}
+	for name, h := range handlers {
+		w := httptest.NewRecorder()
+		if r := serve(Recover(h), w); r != http.ErrAbortHandler {
+			t.Errorf("%s: panicked with %v, want http.ErrAbortHandler", name, r)
+		}
+		if strings.Contains(w.Body.String(), "status") {
+			t.Errorf("%s: problem written after the response started: %s", name, w.Body)
+		}
+	}
+}
+
+func TestRecoverAbort(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	if r := serve(h, httptest.NewRecorder()); r != http.ErrAbortHandler {
+		t.Errorf("panicked with %v, want http.ErrAbortHandler", r)
+	}
+}
+
+// brokenWriter fails every write, like a closed connection
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w brokenWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestRecoverProblemFails(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+	if r := serve(h, brokenWriter{httptest.NewRecorder()}); r != http.ErrAbortHandler {
+		t.Errorf("panicked with %v, want http.ErrAbortHandler", r)
+	}
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	w := httptest.NewRecorder()
+	if r := serve(h, w); r != nil || w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("panic %v, status %d, body %q", r, w.Code, w.Body)
+	}
+}
+
+func TestSafeCall(t *testing.T) {
+	err := SafeCall(func() error { panic("boom") })
+	var perr *group.PanicError
+	if !errors.As(err, &perr) || perr.Value != "boom" || len(perr.Stack) == 0 {
+		t.Errorf("got %#v", err)
+	}
+
+	defer func() {
+		if _, ok := recover().(interface{ RuntimeError() }); !ok {
+			t.Error("runtime error not repanicked")
+		}
+	}()
+	var m map[string]int
+	SafeCall(func() error {
+		m["x"] = 1
+		return nil
+	}, RepanicRuntimeErrors())
+}
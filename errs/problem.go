@@ -0,0 +1,74 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Problem is an RFC 9457 problem details object. Code and Fields are
+// extension members.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code,omitempty"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
+// Problem describes err. The details of server errors (5xx) are left out,
+// since they may expose internals.
+func (r *Registry) Problem(err error, instance string) Problem {
+	m := r.Lookup(err)
+	p := Problem{
+		Type:     m.Type,
+		Title:    http.StatusText(m.HTTPStatus),
+		Status:   m.HTTPStatus,
+		Instance: instance,
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = "Error"
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		p.Code = e.Code.String()
+		if len(e.Fields) > 0 && m.HTTPStatus < 500 {
+			p.Fields = make(map[string]any, len(e.Fields))
+			for _, f := range e.Fields {
+				p.Fields[f.Key] = f.Value
+			}
+		}
+	}
+	if err != nil && m.HTTPStatus < 500 {
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// WriteProblem writes err to w as application/problem+json using
+// DefaultRegistry. The status is already sent when the body fails to be
+// written, so the returned error can only be logged.
+func WriteProblem(w http.ResponseWriter, req *http.Request, err error) error {
+	p := DefaultRegistry.Problem(err, req.URL.Path)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		return fmt.Errorf("errs: writing problem details: %w", err)
+	}
+	return nil
+}
+
+// JSON renders the problem, indented
+func (p Problem) JSON() string {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,93 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	r := NewRegistry()
+	r.Register(1000, Mapping{HTTPStatus: 402, Type: "https://example.com/quota"})
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{"client error", New(NotFound, "user missing", "user", 7), Problem{
+			Type: "about:blank", Title: "Not Found", Status: 404, Instance: "/users/7",
+			Detail: "3 - user missing user=7", Code: "not_found", Fields: map[string]any{"user": 7},
+		}},
+		{"custom type", New(1000, "quota used"), Problem{
+			Type: "https://example.com/quota", Title: "Payment Required", Status: 402, Instance: "/users/7",
+			Detail: "1000 - quota used", Code: "code_1000",
+		}},
+		// details of server errors may expose internals
+		{"server error", New(Internal, "db password wrong", "host", "db1"), Problem{
+			Type: "about:blank", Title: "Internal Server Error", Status: 500, Instance: "/users/7", Code: "internal",
+		}},
+		{"plain error", io.EOF, Problem{
+			Type: "about:blank", Title: "Internal Server Error", Status: 500, Instance: "/users/7",
+		}},
+		{"unknown status", New(Canceled, "gone"), Problem{
+			Type: "about:blank", Title: "Error", Status: 499, Instance: "/users/7",
+			Detail: "11 - gone", Code: "canceled",
+		}},
+	}
+	for _, tt := range tests {
+		if got := r.Problem(tt.err, "/users/7"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/users/7", nil)
+	if err := WriteProblem(w, req, New(NotFound, "user missing", "user", 7)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("content type %q", ct)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type": "about:blank", "title": "Not Found", "status": 404.0, "instance": "/users/7",
+		"detail": "3 - user missing user=7", "code": "not_found", "fields": map[string]any{"user": 7.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	fw := &failingWriter{httptest.NewRecorder()}
+	if err := WriteProblem(fw, req, io.EOF); !errors.Is(err, errWrite) {
+		t.Errorf("failed write: got %v, want %v", err, errWrite)
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	p := Problem{Type: "about:blank", Title: "Not Found", Status: 404}
+	want := "{\n  \"type\": \"about:blank\",\n  \"title\": \"Not Found\",\n  \"status\": 404\n}"
+	if got := p.JSON(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+var errWrite = errors.New("connection reset")
+
+// failingWriter fails to write the body
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w *failingWriter) Write([]byte) (int, error) { return 0, errWrite }
@@ -0,0 +1,121 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+)
+
+// stackDepth is the number of frames captured, 0 disables capture
+var stackDepth atomic.Int32
+
+func init() {
+	stackDepth.Store(32)
+}
+
+// SetStackDepth caps the number of frames captured when an error is
+// created. 0 disables capture, for hot paths where errors are expected.
+func SetStackDepth(n int) {
+	if n < 0 {
+		n = 0
+	}
+	stackDepth.Store(int32(n))
+}
+
+// callers captures the stack of the caller of the constructor calling it
+func callers() []uintptr {
+	depth := stackDepth.Load()
+	if depth == 0 {
+		return nil
+	}
+	pcs := make([]uintptr, depth)
+	// skip runtime.Callers, callers and the constructor
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// stacker is implemented by the errors of this package holding a stack
+type stacker interface {
+	callers() []uintptr
+}
+
+func (e *Error) callers() []uintptr {
+	return e.stack
+}
+
+// hasStack tells whether an error in the chain of err has a stack
+func hasStack(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if s, ok := err.(stacker); ok && len(s.callers()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StackTrace returns the frames where the innermost error with a stack in
+// the chain of err was created, nil if there is none
+func StackTrace(err error) []runtime.Frame {
+	var pcs []uintptr
+	for ; err != nil; err = errors.Unwrap(err) {
+		if s, ok := err.(stacker); ok && len(s.callers()) > 0 {
+			pcs = s.callers()
+		}
+	}
+	if len(pcs) == 0 {
+		return nil
+	}
+	var list []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		list = append(list, frame)
+		if !more {
+			break
+		}
+	}
+	return list
+}
+
+// withStack adds a stack to an error from elsewhere
+type withStack struct {
+	err   error
+	stack []uintptr
+}
+
+// WithStack records where err was seen first, unless it already has a
+// stack. The message of err is unchanged.
+func WithStack(err error) error {
+	if err == nil || hasStack(err) {
+		return err
+	}
+	return &withStack{err: err, stack: callers()}
+}
+
+func (e *withStack) Error() string                 { return e.err.Error() }
+func (e *withStack) Unwrap() error                 { return e.err }
+func (e *withStack) callers() []uintptr            { return e.stack }
+func (e *withStack) Format(s fmt.State, verb rune) { format(e, s, verb) }
+
+// Format prints the stack after the message with %+v
+func (e *Error) Format(s fmt.State, verb rune) {
+	format(e, s, verb)
+}
+
+func format(err error, s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, err.Error())
+			for _, frame := range StackTrace(err) {
+				fmt.Fprintf(s, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+			}
+			return
+		}
+		fmt.Fprintf(s, fmt.FormatString(s, 's'), err.Error())
+	case 's', 'q':
+		fmt.Fprintf(s, fmt.FormatString(s, verb), err.Error())
+	}
+}
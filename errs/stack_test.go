@@ -0,0 +1,91 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// the stacks must start in the function creating the error
+func newHere() *Error      { return New(Internal, "here") }
+func wrapHere() error      { return Wrap(io.EOF, Internal, "wrapped") }
+func withStackHere() error { return WithStack(io.EOF) }
+
+func TestStackTrace(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string // function of the first frame, "" for no stack
+	}{
+		{"New", newHere(), "newHere"},
+		{"Wrap", wrapHere(), "wrapHere"},
+		{"WithStack", withStackHere(), "withStackHere"},
+		// the innermost stack tells where the problem started
+		{"Wrap keeps the stack of the cause", Wrap(newHere(), Unavailable, "outer"), "newHere"},
+		{"WithStack keeps the stack", WithStack(fmt.Errorf("a: %w", newHere())), "newHere"},
+		{"plain error", io.EOF, ""},
+		{"sentinel", ErrNotFound, ""},
+	}
+	for _, tt := range tests {
+		frames := StackTrace(tt.err)
+		if tt.want == "" {
+			if frames != nil {
+				t.Errorf("%s: got %d frames, want none", tt.name, len(frames))
+			}
+			continue
+		}
+		if len(frames) == 0 || !strings.HasSuffix(frames[0].Function, "."+tt.want) {
+			t.Errorf("%s: stack does not start in %s: %v", tt.name, tt.want, frames)
+		}
+	}
+}
+
+func TestSetStackDepth(t *testing.T) {
+	defer SetStackDepth(32)
+	SetStackDepth(0)
+	if frames := StackTrace(newHere()); frames != nil {
+		t.Errorf("depth 0: got %d frames", len(frames))
+	}
+	SetStackDepth(2)
+	if frames := StackTrace(newHere()); len(frames) != 2 {
+		t.Errorf("depth 2: got %d frames", len(frames))
+	}
+	SetStackDepth(-1)
+	if frames := StackTrace(newHere()); frames != nil {
+		t.Errorf("depth -1: got %d frames", len(frames))
+	}
+}
+
+func TestFormat(t *testing.T) {
+	err := Wrap(newHere(), Unavailable, "outer")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%v", "9 - outer: 1 - here"},
+		{"%s", "9 - outer: 1 - here"},
+		{"%q", `"9 - outer: 1 - here"`},
+		{"%20v", fmt.Sprintf("%20s", "9 - outer: 1 - here")},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, err); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	detailed := fmt.Sprintf("%+v", err)
+	lines := strings.Split(detailed, "\n")
+	if lines[0] != "9 - outer: 1 - here" {
+		t.Errorf("%%+v: first line %q", lines[0])
+	}
+	// function and file:line pairs, starting where the cause was created
+	if len(lines) < 3 || !strings.HasSuffix(lines[1], ".newHere") || !strings.Contains(lines[2], "stack_test.go:") {
+		t.Errorf("%%+v: got\n%s", detailed)
+	}
+
+	if got := fmt.Sprintf("%+v", WithStack(errors.New("plain"))); !strings.HasPrefix(got, "plain\n") {
+		t.Errorf("WithStack %%+v: got %q", got)
+	}
+}
@@ -0,0 +1,234 @@
+// Package events shapes streams of events: Debounce waits for a quiet
+// period, Throttle limits the rate, Coalesce merges bursts and Batch groups
+// values by count or time.
+//
+// Each one exists as a type fed with Push, which is deterministic when used
+// with a schedule.FakeClock, and as a function working on channels.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+// Option configures the event utilities
+type Option func(*config)
+
+type config struct {
+	clock    schedule.Clock
+	leading  bool
+	trailing bool
+}
+
+func configure(opts []Option) config {
+	c := config{clock: schedule.RealClock, leading: true, trailing: true}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithClock replaces the real clock
+func WithClock(c schedule.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// Coalescer merges values with a reducer and emits the result when the
+// burst ends: after a quiet period, after a maximum wait since the first
+// value, or once enough values were merged. Unused limits are zero.
+type Coalescer[T, A any] struct {
+	quiet   time.Duration
+	maxWait time.Duration
+	maxSize int
+	reduce  func(A, T) A
+	emit    func(A)
+	clock   schedule.Clock
+
+	mu      sync.Mutex
+	acc     A
+	n       int
+	gen     int
+	quietT  schedule.Timer
+	maxT    schedule.Timer
+	stopped bool
+
+	// emitMu keeps emissions in order and lets Stop wait for them
+	emitMu sync.Mutex
+}
+
+// NewCoalescer creates a coalescer calling emit with the merged value of
+// each burst
+func NewCoalescer[T, A any](quiet, maxWait time.Duration, maxSize int, reduce func(A, T) A, emit func(A), opts ...Option) *Coalescer[T, A] {
+	cfg := configure(opts)
+	return &Coalescer[T, A]{
+		quiet:   quiet,
+		maxWait: maxWait,
+		maxSize: maxSize,
+		reduce:  reduce,
+		emit:    emit,
+		clock:   cfg.clock,
+	}
+}
+
+// NewDebouncer emits the last value once no value arrived for quiet
+func NewDebouncer[T any](quiet time.Duration, emit func(T), opts ...Option) *Coalescer[T, T] {
+	last := func(_ T, v T) T {
+		return v
+	}
+	return NewCoalescer(quiet, 0, 0, last, emit, opts...)
+}
+
+// NewBatcher emits slices of up to size values, or what was collected after
+// maxWait since the first value of the batch
+func NewBatcher[T any](size int, maxWait time.Duration, emit func([]T), opts ...Option) *Coalescer[T, []T] {
+	return NewCoalescer(0, maxWait, size, func(batch []T, v T) []T {
+		return append(batch, v)
+	}, emit, opts...)
+}
+
+// Push adds a value to the current burst
+func (c *Coalescer[T, A]) Push(v T) {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.acc = c.reduce(c.acc, v)
+	c.n++
+	gen := c.gen
+	if c.n == 1 && c.maxWait > 0 {
+		c.maxT = c.clock.AfterFunc(c.maxWait, func() { c.fire(gen) })
+	}
+	if c.quiet > 0 {
+		if c.quietT != nil {
+			c.quietT.Stop()
+		}
+		c.quietT = c.clock.AfterFunc(c.quiet, func() { c.fire(gen) })
+	}
+	if c.maxSize > 0 && c.n >= c.maxSize {
+		c.flushLocked()
+		return
+	}
+	c.mu.Unlock()
+}
+
+// Flush emits the current burst right away, if there is one
+func (c *Coalescer[T, A]) Flush() {
+	c.mu.Lock()
+	if c.n == 0 || c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.flushLocked()
+}
+
+// Stop discards the current burst and waits for an emission in progress
+func (c *Coalescer[T, A]) Stop() {
+	c.mu.Lock()
+	c.stopped = true
+	c.reset()
+	c.mu.Unlock()
+	c.emitMu.Lock()
+	c.emitMu.Unlock()
+}
+
+func (c *Coalescer[T, A]) fire(gen int) {
+	c.mu.Lock()
+	if gen != c.gen || c.n == 0 || c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.flushLocked()
+}
+
+// flushLocked takes the burst and emits it. It is called with c.mu held
+// and releases it.
+func (c *Coalescer[T, A]) flushLocked() {
+	v := c.acc
+	c.reset()
+	c.emitMu.Lock()
+	c.mu.Unlock()
+	defer c.emitMu.Unlock()
+	c.emit(v)
+}
+
+// reset starts a new burst. c.mu must be held.
+func (c *Coalescer[T, A]) reset() {
+	var zero A
+	c.acc = zero
+	c.n = 0
+	c.gen++
+	if c.quietT != nil {
+		c.quietT.Stop()
+		c.quietT = nil
+	}
+	if c.maxT != nil {
+		c.maxT.Stop()
+		c.maxT = nil
+	}
+}
+
+// pusher is the common part of Coalescer and Throttler
+type pusher[T any] interface {
+	Push(T)
+	Flush()
+	Stop()
+}
+
+// sender returns an emit function writing to out until ctx is done
+func sender[T any](ctx context.Context, out chan<- T) func(T) {
+	return func(v T) {
+		select {
+		case out <- v:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// pump feeds p from in. When in is closed the pending values are flushed,
+// when ctx is done they are dropped. Either way out is closed at the end.
+func pump[T, O any](ctx context.Context, in <-chan T, p pusher[T], out chan O) {
+	defer close(out)
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				p.Flush()
+				p.Stop()
+				return
+			}
+			p.Push(v)
+		case <-ctx.Done():
+			p.Stop()
+			return
+		}
+	}
+}
+
+// Debounce emits a value once in has been quiet for the given time
+func Debounce[T any](ctx context.Context, in <-chan T, quiet time.Duration, opts ...Option) <-chan T {
+	out := make(chan T)
+	go pump(ctx, in, NewDebouncer(quiet, sender(ctx, out), opts...), out)
+	return out
+}
+
+// Coalesce merges the values of each burst with reduce, starting from the
+// zero value of A. A burst ends after quiet without new values.
+func Coalesce[T, A any](ctx context.Context, in <-chan T, quiet time.Duration, reduce func(A, T) A, opts ...Option) <-chan A {
+	out := make(chan A)
+	go pump(ctx, in, NewCoalescer(quiet, 0, 0, reduce, sender(ctx, out), opts...), out)
+	return out
+}
+
+// Batch emits slices of size values, or fewer when maxWait has passed since
+// the first value of the slice
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration, opts ...Option) <-chan []T {
+	out := make(chan []T)
+	go pump(ctx, in, NewBatcher(size, maxWait, sender(ctx, out), opts...), out)
+	return out
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+var start = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// log records emitted values with the fake time they were emitted at
+type log struct {
+	clock *schedule.FakeClock
+	mu    sync.Mutex
+	lines []string
+}
+
+func newLog() *log {
+	return &log{clock: schedule.NewFakeClock(start)}
+}
+
+func (l *log) emit(v any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf("%v@%v", v, l.clock.Now().Sub(start)))
+}
+
+func (l *log) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, " ")
+}
+
+// at advances the clock to the offset d since start
+func (l *log) at(d time.Duration) {
+	l.clock.Advance(d - l.clock.Now().Sub(start))
+}
+
+func (l *log) check(t *testing.T, want string) {
+	t.Helper()
+	if got := l.String(); got != want {
+		t.Errorf("emitted %q, want %q", got, want)
+	}
+}
+
+const ms = time.Millisecond
+
+func TestDebouncer(t *testing.T) {
+	l := newLog()
+	d := NewDebouncer(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock))
+	d.Push("a")
+	l.at(50 * ms)
+	d.Push("b")
+	l.at(149 * ms)
+	l.check(t, "")
+	// exactly quiet after the last value
+	l.at(150 * ms)
+	l.check(t, "b@150ms")
+
+	d.Push("c")
+	l.at(400 * ms)
+	l.check(t, "b@150ms c@250ms")
+}
+
+func TestCoalescerMaxWait(t *testing.T) {
+	l := newLog()
+	sum := func(acc, v int) int { return acc + v }
+	c := NewCoalescer(100*ms, 250*ms, 0, sum, func(v int) { l.emit(v) }, WithClock(l.clock))
+	// a value every 50ms never lets the burst go quiet
+	for i := 0; i < 10; i++ {
+		l.at(time.Duration(i) * 50 * ms)
+		c.Push(1)
+	}
+	// the maximum wait fired at 250ms before the push made then
+	l.check(t, "5@250ms")
+	// and at 500ms for the burst started at 250ms
+	l.at(499 * ms)
+	l.check(t, "5@250ms")
+	l.at(500 * ms)
+	l.check(t, "5@250ms 5@500ms")
+	l.at(time.Second)
+	l.check(t, "5@250ms 5@500ms")
+}
+
+func TestBatcher(t *testing.T) {
+	l := newLog()
+	b := NewBatcher(3, time.Second, func(v []int) { l.emit(v) }, WithClock(l.clock))
+	for i := 1; i <= 7; i++ {
+		b.Push(i)
+	}
+	l.at(999 * ms)
+	l.check(t, "[1 2 3]@0s [4 5 6]@0s")
+	l.at(time.Second)
+	l.check(t, "[1 2 3]@0s [4 5 6]@0s [7]@1s")
+
+	// a full batch stops the timer of its maximum wait
+	b.Push(8)
+	l.at(1500 * ms)
+	b.Push(9)
+	b.Push(10)
+	l.at(3 * time.Second)
+	l.check(t, "[1 2 3]@0s [4 5 6]@0s [7]@1s [8 9 10]@1.5s")
+}
+
+func TestCoalescerFlushStop(t *testing.T) {
+	l := newLog()
+	d := NewDebouncer(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock))
+	d.Push("a")
+	d.Flush()
+	d.Flush()
+	l.at(time.Second)
+	l.check(t, "a@0s")
+
+	d.Push("b")
+	d.Stop()
+	d.Push("c")
+	l.at(2 * time.Second)
+	l.check(t, "a@0s")
+}
+
+func TestBatchChannel(t *testing.T) {
+	l := newLog()
+	in := make(chan int)
+	out := Batch(context.Background(), in, 2, time.Hour, WithClock(l.clock))
+	go func() {
+		for i := 1; i <= 5; i++ {
+			in <- i
+		}
+		// closing flushes the last, partial batch
+		close(in)
+	}()
+	var got []string
+	for batch := range out {
+		got = append(got, fmt.Sprint(batch))
+	}
+	if s := strings.Join(got, " "); s != "[1 2] [3 4] [5]" {
+		t.Errorf("got %s", s)
+	}
+}
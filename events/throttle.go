@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+// Leading sets whether the first value of a quiet stream is emitted at once
+// (true by default)
+func Leading(on bool) Option {
+	return func(c *config) {
+		c.leading = on
+	}
+}
+
+// Trailing sets whether the last value received during an interval is
+// emitted when it ends (true by default)
+func Trailing(on bool) Option {
+	return func(c *config) {
+		c.trailing = on
+	}
+}
+
+// Throttler emits at most one value per interval
+type Throttler[T any] struct {
+	interval time.Duration
+	leading  bool
+	trailing bool
+	emit     func(T)
+	clock    schedule.Clock
+
+	mu         sync.Mutex
+	timer      schedule.Timer // set while an interval is running
+	pending    T
+	hasPending bool
+	stopped    bool
+
+	emitMu sync.Mutex
+}
+
+// NewThrottler creates a throttler calling emit at most once per interval.
+// Without the leading edge the trailing edge is always on, otherwise nothing
+// would ever be emitted.
+func NewThrottler[T any](interval time.Duration, emit func(T), opts ...Option) *Throttler[T] {
+	cfg := configure(opts)
+	if !cfg.leading {
+		cfg.trailing = true
+	}
+	return &Throttler[T]{
+		interval: interval,
+		leading:  cfg.leading,
+		trailing: cfg.trailing,
+		emit:     emit,
+		clock:    cfg.clock,
+	}
+}
+
+// Push offers a value
+func (t *Throttler[T]) Push(v T) {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	if t.timer == nil {
+		t.timer = t.clock.AfterFunc(t.interval, t.intervalEnd)
+		if t.leading {
+			t.emitLocked(v)
+			return
+		}
+	}
+	if t.trailing {
+		t.pending = v
+		t.hasPending = true
+	}
+	t.mu.Unlock()
+}
+
+// Flush emits the pending trailing value right away
+func (t *Throttler[T]) Flush() {
+	t.mu.Lock()
+	if !t.hasPending || t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	v := t.take()
+	t.emitLocked(v)
+}
+
+// Stop drops the pending value and waits for an emission in progress
+func (t *Throttler[T]) Stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.take()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.mu.Unlock()
+	t.emitMu.Lock()
+	t.emitMu.Unlock()
+}
+
+func (t *Throttler[T]) intervalEnd() {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	if !t.hasPending {
+		t.timer = nil
+		t.mu.Unlock()
+		return
+	}
+	// emitting the trailing value starts a new interval
+	v := t.take()
+	t.timer = t.clock.AfterFunc(t.interval, t.intervalEnd)
+	t.emitLocked(v)
+}
+
+func (t *Throttler[T]) take() T {
+	var zero T
+	v := t.pending
+	t.pending = zero
+	t.hasPending = false
+	return v
+}
+
+// emitLocked is called with t.mu held and releases it
+func (t *Throttler[T]) emitLocked(v T) {
+	t.emitMu.Lock()
+	t.mu.Unlock()
+	defer t.emitMu.Unlock()
+	t.emit(v)
+}
+
+// Throttle forwards at most one value of in per interval
+func Throttle[T any](ctx context.Context, in <-chan T, interval time.Duration, opts ...Option) <-chan T {
+	out := make(chan T)
+	go pump(ctx, in, NewThrottler(interval, sender(ctx, out), opts...), out)
+	return out
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThrottleEdges(t *testing.T) {
+	l := newLog()
+	th := NewThrottler(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock))
+	th.Push("a")
+	l.at(30 * ms)
+	th.Push("b")
+	l.at(60 * ms)
+	th.Push("c")
+	l.check(t, "a@0s")
+
+	// the trailing value ends the interval and starts a new one, so a value
+	// pushed right at its end waits for the next
+	l.at(100 * ms)
+	th.Push("d")
+	l.at(199 * ms)
+	l.check(t, "a@0s c@100ms")
+	l.at(200 * ms)
+	l.check(t, "a@0s c@100ms d@200ms")
+
+	// an interval without values ends the throttling, the next value is
+	// leading again
+	l.at(350 * ms)
+	th.Push("e")
+	l.check(t, "a@0s c@100ms d@200ms e@350ms")
+}
+
+func TestThrottleLeadingOnly(t *testing.T) {
+	l := newLog()
+	th := NewThrottler(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock), Trailing(false))
+	th.Push("a")
+	l.at(99 * ms)
+	th.Push("b")
+	l.at(100 * ms)
+	th.Push("c")
+	l.at(time.Second)
+	l.check(t, "a@0s c@100ms")
+}
+
+func TestThrottleTrailingOnly(t *testing.T) {
+	l := newLog()
+	th := NewThrottler(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock), Leading(false))
+	th.Push("a")
+	l.at(40 * ms)
+	th.Push("b")
+	l.at(time.Second)
+	l.check(t, "b@100ms")
+
+	// disabling both edges keeps the trailing one
+	l = newLog()
+	th = NewThrottler(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock), Leading(false), Trailing(false))
+	th.Push("a")
+	l.at(time.Second)
+	l.check(t, "a@100ms")
+}
+
+func TestThrottleFlushStop(t *testing.T) {
+	l := newLog()
+	th := NewThrottler(100*ms, func(v string) { l.emit(v) }, WithClock(l.clock))
+	th.Push("a")
+	th.Push("b")
+	th.Flush()
+	l.check(t, "a@0s b@0s")
+	// nothing left for the end of the interval
+	l.at(100 * ms)
+	l.check(t, "a@0s b@0s")
+
+	th.Push("c")
+	th.Push("d")
+	th.Stop()
+	l.at(time.Second)
+	l.check(t, "a@0s b@0s c@100ms")
+}
+
+func TestThrottleChannel(t *testing.T) {
+	l := newLog()
+	in := make(chan int)
+	out := Throttle(context.Background(), in, time.Hour, WithClock(l.clock))
+	go func() {
+		for i := 1; i <= 5; i++ {
+			in <- i
+		}
+		close(in)
+	}()
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	// the leading value, and the trailing one flushed when in is closed
+	if len(got) != 2 || got[0] != 1 || got[1] != 5 {
+		t.Errorf("got %v, want [1 5]", got)
+	}
+}
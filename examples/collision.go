@@ -0,0 +1,440 @@
+package examples
+
+import (
+	"math"
+)
+
+// Containment and collision queries
+
+// ellipseSides is how finely an ellipse is approximated when tested
+// against another curved shape
+const ellipseSides = 256
+
+func sub(a, b Point) Point         { return Point{a.X - b.X, a.Y - b.Y} }
+func dot(a, b Point) float64       { return a.X*b.X + a.Y*b.Y }
+func cross(a, b Point) float64     { return a.X*b.Y - a.Y*b.X }
+func orient(a, b, c Point) float64 { return cross(sub(b, a), sub(c, a)) }
+
+// segmentDistance is the distance from p to the segment ab
+func segmentDistance(p, a, b Point) float64 {
+	ab := sub(b, a)
+	l := dot(ab, ab)
+	if l == 0 {
+		return p.dist(a)
+	}
+	t := math.Max(0, math.Min(1, dot(sub(p, a), ab)/l))
+	return p.dist(Point{a.X + t*ab.X, a.Y + t*ab.Y})
+}
+
+// segmentsMeet tells whether the segments ab and cd touch or cross
+func segmentsMeet(a, b, c, d Point) bool {
+	d1, d2 := orient(c, d, a), orient(c, d, b)
+	d3, d4 := orient(a, b, c), orient(a, b, d)
+	if (d1 > 0 && d2 < 0 || d1 < 0 && d2 > 0) && (d3 > 0 && d4 < 0 || d3 < 0 && d4 > 0) {
+		return true
+	}
+	near := func(p, a, b Point) bool {
+		return ShapeTolerance.LessOrEqual(segmentDistance(p, a, b), 0)
+	}
+	return near(a, c, d) || near(b, c, d) || near(c, a, b) || near(d, a, b)
+}
+
+// polygonContains uses the even-odd rule, counting the border as inside
+func polygonContains(vertices []Point, p Point) bool {
+	inside := false
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		if ShapeTolerance.LessOrEqual(segmentDistance(p, a, b), 0) {
+			return true
+		}
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// convex tells whether the polygon turns always the same way
+func convex(vertices []Point) bool {
+	sign := 0.0
+	for i, a := range vertices {
+		turn := orient(a, vertices[(i+1)%len(vertices)], vertices[(i+2)%len(vertices)])
+		if ShapeTolerance.Equal(turn, 0) {
+			continue
+		}
+		if sign != 0 && turn*sign < 0 {
+			return false
+		}
+		sign = turn
+	}
+	return true
+}
+
+// separated looks for a separating axis between two convex polygons.
+// Degenerate polygons, like segments, also try their own direction.
+func separated(p, q []Point) bool {
+	var axes []Point
+	for _, poly := range [][]Point{p, q} {
+		for i, a := range poly {
+			e := sub(poly[(i+1)%len(poly)], a)
+			if e == (Point{}) {
+				continue
+			}
+			axes = append(axes, Point{-e.Y, e.X})
+			if len(poly) <= 2 {
+				axes = append(axes, e)
+			}
+		}
+	}
+	project := func(poly []Point, axis Point) (float64, float64) {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range poly {
+			x := dot(v, axis)
+			lo, hi = math.Min(lo, x), math.Max(hi, x)
+		}
+		return lo, hi
+	}
+	for _, axis := range axes {
+		l := math.Hypot(axis.X, axis.Y)
+		axis = Point{axis.X / l, axis.Y / l}
+		pl, ph := project(p, axis)
+		ql, qh := project(q, axis)
+		if !ShapeTolerance.LessOrEqual(ql, ph) || !ShapeTolerance.LessOrEqual(pl, qh) {
+			return true
+		}
+	}
+	return false
+}
+
+// polygonsMeet works for any simple polygons: either two edges meet or one
+// polygon is inside the other
+func polygonsMeet(p, q []Point) bool {
+	if convex(p) && convex(q) {
+		return !separated(p, q)
+	}
+	for i, a := range p {
+		for j, c := range q {
+			if segmentsMeet(a, p[(i+1)%len(p)], c, q[(j+1)%len(q)]) {
+				return true
+			}
+		}
+	}
+	return polygonContains(p, q[0]) || polygonContains(q, p[0])
+}
+
+// circleMeetsPolygon tells whether the disk touches the polygon
+func circleMeetsPolygon(c circle, vertices []Point) bool {
+	if len(vertices) > 2 && polygonContains(vertices, c.center) {
+		return true
+	}
+	for i, a := range vertices {
+		if ShapeTolerance.LessOrEqual(segmentDistance(c.center, a, vertices[(i+1)%len(vertices)]), c.radius) {
+			return true
+		}
+	}
+	return false
+}
+
+// degenerate tells whether a shape meant to have an area has none, like a
+// polygon with fewer than 3 vertices or an ellipse without an axis. It
+// contains no point and meets no shape. Segments are never degenerate.
+func degenerate(s Shape) bool {
+	switch s := s.(type) {
+	case LineSegment, Composite:
+		return false
+	case Polygon:
+		if len(s.Vertices) < 3 {
+			return true
+		}
+	case RegularPolygon:
+		if s.Sides < 3 {
+			return true
+		}
+	}
+	return ShapeTolerance.LessOrEqual(s.area(), 0)
+}
+
+// outline returns the vertices of shapes bounded by straight edges
+func outline(s Shape) ([]Point, bool) {
+	switch s := s.(type) {
+	case rect:
+		return s.corners(), true
+	case Triangle:
+		return []Point{s.A, s.B, s.C}, true
+	case RegularPolygon:
+		return s.Vertices(), true
+	case Polygon:
+		return s.Vertices, true
+	case LineSegment:
+		return []Point{s.A, s.B}, true
+	}
+	return nil, false
+}
+
+// toUnit maps the ellipse onto the unit circle at the origin
+func (e Ellipse) toUnit() Affine {
+	return Translation(-e.Center.X, -e.Center.Y).Then(Rotation(-e.Angle)).Then(Scaling(1/e.A, 1/e.B))
+}
+
+// polygon approximates the ellipse
+func (e Ellipse) polygon() Polygon {
+	vs := make([]Point, ellipseSides)
+	m := Scaling(e.A, e.B).Then(Rotation(e.Angle)).Then(Translation(e.Center.X, e.Center.Y))
+	for i := range vs {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / ellipseSides)
+		vs[i] = m.Apply(Point{cos, sin})
+	}
+	return Polygon{vs}
+}
+
+// intersects tells whether two shapes have at least a point in common.
+// Ellipses are exact against straight edged shapes, and approximated by a
+// polygon of ellipseSides sides against circles and other ellipses.
+func intersects(a, b Shape) bool {
+	if degenerate(a) || degenerate(b) {
+		return false
+	}
+	if c, ok := a.(Composite); ok {
+		for _, s := range c.Shapes {
+			if intersects(s, b) {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := b.(Composite); ok {
+		return intersects(b, a)
+	}
+
+	if e, ok := a.(Ellipse); ok {
+		switch b.(type) {
+		case Ellipse, circle:
+			return intersects(e.polygon(), b)
+		}
+		return intersects(circle{radius: 1}, b.Transform(e.toUnit()))
+	}
+	if _, ok := b.(Ellipse); ok {
+		return intersects(b, a)
+	}
+
+	ca, aCircle := a.(circle)
+	cb, bCircle := b.(circle)
+	pa, _ := outline(a)
+	pb, _ := outline(b)
+	switch {
+	case aCircle && bCircle:
+		return ShapeTolerance.LessOrEqual(ca.center.dist(cb.center), ca.radius+cb.radius)
+	case aCircle:
+		return circleMeetsPolygon(ca, pb)
+	case bCircle:
+		return circleMeetsPolygon(cb, pa)
+	}
+	return polygonsMeet(pa, pb)
+}
+
+// Contains tells whether p is inside the rect or on its border
+func (r rect) Contains(p Point) bool {
+	if degenerate(r) {
+		return false
+	}
+	local := Translation(-r.center.X, -r.center.Y).Then(Rotation(-r.angle)).Apply(p)
+	return ShapeTolerance.LessOrEqual(math.Abs(local.X), r.width/2) &&
+		ShapeTolerance.LessOrEqual(math.Abs(local.Y), r.height/2)
+}
+
+// Contains tells whether p is inside the circle or on its border
+func (c circle) Contains(p Point) bool {
+	return !degenerate(c) && ShapeTolerance.LessOrEqual(c.center.dist(p), c.radius)
+}
+
+// Contains tells whether p is inside the ellipse or on its border
+func (e Ellipse) Contains(p Point) bool {
+	if degenerate(e) {
+		return false
+	}
+	local := e.toUnit().Apply(p)
+	return ShapeTolerance.LessOrEqual(math.Hypot(local.X, local.Y), 1)
+}
+
+// Contains tells whether p is inside the triangle or on its border
+func (t Triangle) Contains(p Point) bool {
+	return !degenerate(t) && polygonContains([]Point{t.A, t.B, t.C}, p)
+}
+
+// Contains tells whether p is inside the polygon or on its border
+func (r RegularPolygon) Contains(p Point) bool {
+	return !degenerate(r) && polygonContains(r.Vertices(), p)
+}
+
+// Contains tells whether p is inside the polygon or on its border
+func (p Polygon) Contains(q Point) bool {
+	return !degenerate(p) && polygonContains(p.Vertices, q)
+}
+
+// Contains tells whether p is on the segment
+func (l LineSegment) Contains(p Point) bool {
+	return ShapeTolerance.LessOrEqual(segmentDistance(p, l.A, l.B), 0)
+}
+
+// Contains tells whether p is in one of the children
+func (c Composite) Contains(p Point) bool {
+	for _, s := range c.Shapes {
+		if s.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersects tells whether the shapes have a point in common
+func (r rect) Intersects(o Shape) bool { return intersects(r, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (c circle) Intersects(o Shape) bool { return intersects(c, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (e Ellipse) Intersects(o Shape) bool { return intersects(e, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (t Triangle) Intersects(o Shape) bool { return intersects(t, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (r RegularPolygon) Intersects(o Shape) bool { return intersects(r, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (p Polygon) Intersects(o Shape) bool { return intersects(p, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (l LineSegment) Intersects(o Shape) bool { return intersects(l, o) }
+
+// Intersects tells whether the shapes have a point in common
+func (c Composite) Intersects(o Shape) bool { return intersects(c, o) }
+
+// IntersectionArea returns the area shared by two rects or circles, in any
+// combination. ok is false for other shapes.
+func IntersectionArea(a, b Shape) (area float64, ok bool) {
+	ra, aRect := a.(rect)
+	rb, bRect := b.(rect)
+	ca, aCircle := a.(circle)
+	cb, bCircle := b.(circle)
+	if (aRect || aCircle) && (bRect || bCircle) && (degenerate(a) || degenerate(b)) {
+		return 0, true
+	}
+	switch {
+	case aRect && bRect:
+		return polygonArea(clipConvex(ra.corners(), rb.corners())), true
+	case aCircle && bCircle:
+		return lensArea(ca, cb), true
+	case aCircle && bRect:
+		return circlePolygonArea(ca, rb.corners()), true
+	case aRect && bCircle:
+		return circlePolygonArea(cb, ra.corners()), true
+	}
+	return 0, false
+}
+
+func polygonArea(vertices []Point) float64 {
+	if len(vertices) < 3 {
+		return 0
+	}
+	return Polygon{vertices}.area()
+}
+
+// clipConvex cuts subject with each edge of the convex polygon clip
+// (Sutherland-Hodgman)
+func clipConvex(subject, clip []Point) []Point {
+	if orient(clip[0], clip[1], clip[2]) < 0 {
+		clip = reversed(clip)
+	}
+	out := subject
+	for i, a := range clip {
+		b := clip[(i+1)%len(clip)]
+		in := out
+		out = nil
+		inside := func(p Point) bool { return orient(a, b, p) >= 0 }
+		for j, p := range in {
+			q := in[(j+1)%len(in)]
+			if inside(p) {
+				out = append(out, p)
+			}
+			if inside(p) != inside(q) {
+				// where pq crosses the line ab
+				t := orient(a, b, p) / (orient(a, b, p) - orient(a, b, q))
+				out = append(out, Point{p.X + t*(q.X-p.X), p.Y + t*(q.Y-p.Y)})
+			}
+		}
+		if len(out) == 0 {
+			break
+		}
+	}
+	return out
+}
+
+func reversed(points []Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[len(points)-1-i] = p
+	}
+	return out
+}
+
+// lensArea is the area shared by two circles
+func lensArea(a, b circle) float64 {
+	d := a.center.dist(b.center)
+	switch {
+	case d >= a.radius+b.radius:
+		return 0
+	case d <= math.Abs(a.radius-b.radius):
+		r := math.Min(a.radius, b.radius)
+		return math.Pi * r * r
+	}
+	r1, r2 := a.radius, b.radius
+	alpha := math.Acos((d*d + r1*r1 - r2*r2) / (2 * d * r1))
+	beta := math.Acos((d*d + r2*r2 - r1*r1) / (2 * d * r2))
+	return r1*r1*(alpha-math.Sin(2*alpha)/2) + r2*r2*(beta-math.Sin(2*beta)/2)
+}
+
+// circlePolygonArea is the area shared by a circle and a simple polygon: the
+// sum of the signed areas the circle shares with the triangles made by its
+// center and each edge
+func circlePolygonArea(c circle, vertices []Point) float64 {
+	sum := 0.0
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		sum += circleTriangleArea(sub(a, c.center), sub(b, c.center), c.radius)
+	}
+	return math.Abs(sum)
+}
+
+// circleTriangleArea is the signed area shared by the circle of radius r at
+// the origin and the triangle (origin, a, b)
+func circleTriangleArea(a, b Point, r float64) float64 {
+	// split ab where it crosses the circle, solving |a + t(b-a)| = r
+	d := sub(b, a)
+	points := []Point{a}
+	qa, qb, qc := dot(d, d), 2*dot(a, d), dot(a, a)-r*r
+	disc := qb*qb - 4*qa*qc
+	if qa > 0 && disc > 0 {
+		sq := math.Sqrt(disc)
+		for _, t := range []float64{(-qb - sq) / (2 * qa), (-qb + sq) / (2 * qa)} {
+			if t > 0 && t < 1 {
+				points = append(points, Point{a.X + t*d.X, a.Y + t*d.Y})
+			}
+		}
+	}
+	points = append(points, b)
+
+	area := 0.0
+	for i := 0; i+1 < len(points); i++ {
+		p, q := points[i], points[i+1]
+		mid := Point{(p.X + q.X) / 2, (p.Y + q.Y) / 2}
+		if disc > 0 && dot(mid, mid) <= r*r {
+			area += cross(p, q) / 2
+		} else {
+			// outside the circle, or tangent to it, the piece contributes a
+			// sector
+			area += r * r * math.Atan2(cross(p, q), dot(p, q)) / 2
+		}
+	}
+	return area
+}
@@ -0,0 +1,213 @@
+package examples
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// square is the shape most cases are tested against: x and y in [-1, 1]
+var square = rect{width: 2, height: 2}
+
+// uShape has a notch between x 1 and 2, above y 1
+var uShape = Polygon{[]Point{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}}}
+
+// degenerates have an area in theory but none in practice
+var degenerates = []Shape{
+	Polygon{},
+	Polygon{[]Point{{0, 0}, {1, 1}}},
+	Polygon{[]Point{{-1, 0}, {0, 0}, {1, 0}}},
+	RegularPolygon{Sides: 0, Radius: 1},
+	RegularPolygon{Sides: 2, Radius: 1},
+	RegularPolygon{Sides: -1, Radius: 1},
+	RegularPolygon{Sides: 6},
+	Triangle{Point{-1, 0}, Point{0, 0}, Point{1, 0}},
+	Ellipse{},
+	Ellipse{A: 1},
+	rect{width: 2},
+	circle{},
+}
+
+func TestIntersects(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Shape
+		want bool
+	}{
+		{"rect overlapping rect", square, rect{width: 2, height: 2, center: Point{1, 1}}, true},
+		{"rect touching rect", square, rect{width: 2, height: 2, center: Point{2, 0}}, true},
+		{"rect touching rect corner", square, rect{width: 2, height: 2, center: Point{2, 2}}, true},
+		{"rect apart from rect", square, rect{width: 2, height: 2, center: Point{2.5, 0}}, false},
+		{"rect in rect", square, rect{width: 1, height: 1}, true},
+		{"rotated rect touching corner", square, rect{width: 2, height: 2, center: Point{1 + math.Sqrt2, 0}, angle: math.Pi / 4}, true},
+		{"rotated rect apart", square, rect{width: 2, height: 2, center: Point{1.1 + math.Sqrt2, 0}, angle: math.Pi / 4}, false},
+
+		{"circle touching rect", square, circle{radius: 1, center: Point{2, 0}}, true},
+		{"circle apart from rect corner", square, circle{radius: 0.4, center: Point{1.3, 1.3}}, false},
+		{"circle in rect", square, circle{radius: 0.5}, true},
+		{"rect in circle", circle{radius: 3}, square, true},
+		{"circle touching circle", circle{radius: 1}, circle{radius: 2, center: Point{3, 0}}, true},
+		{"circle apart from circle", circle{radius: 1}, circle{radius: 2, center: Point{3.1, 0}}, false},
+		{"circle in circle", circle{radius: 3}, circle{radius: 1, center: Point{1, 0}}, true},
+
+		{"ellipse touching rect", square, Ellipse{A: 1, B: 0.5, Center: Point{2, 0}}, true},
+		{"ellipse apart from rect", square, Ellipse{A: 1, B: 0.5, Center: Point{2.1, 0}}, false},
+		{"rotated ellipse across rect", square, Ellipse{A: 3, B: 0.1, Center: Point{2, 2}, Angle: math.Pi / 4}, true},
+		{"rect in ellipse", Ellipse{A: 4, B: 3}, square, true},
+		{"ellipse in circle", circle{radius: 3}, Ellipse{A: 2, B: 1}, true},
+		{"ellipse near circle", circle{radius: 1, center: Point{0, 1.9}}, Ellipse{A: 2, B: 1}, true},
+		{"ellipse apart from circle", circle{radius: 1, center: Point{0, 2.1}}, Ellipse{A: 2, B: 1}, false},
+		{"ellipse crossing ellipse", Ellipse{A: 2, B: 0.5}, Ellipse{A: 2, B: 0.5, Angle: math.Pi / 2}, true},
+		{"ellipse apart from ellipse", Ellipse{A: 2, B: 0.5}, Ellipse{A: 2, B: 0.5, Center: Point{0, 1.1}}, false},
+
+		{"triangle touching rect", square, Triangle{Point{1, 0}, Point{2, 1}, Point{2, -1}}, true},
+		{"triangle apart from rect", square, Triangle{Point{1.1, 0}, Point{2, 1}, Point{2, -1}}, false},
+		{"triangle in rect", square, Triangle{Point{-0.5, -0.5}, Point{0.5, -0.5}, Point{0, 0.5}}, true},
+		{"triangle touching circle", circle{radius: 1}, Triangle{Point{-1, 1}, Point{1, 1}, Point{0, 2}}, true},
+		{"triangle apart from circle", circle{radius: 1}, Triangle{Point{0.8, 0.8}, Point{2, 0.8}, Point{0.8, 2}}, false},
+
+		{"hexagon in rect", square, RegularPolygon{Sides: 6, Radius: 0.5}, true},
+		{"hexagon touching rect", square, RegularPolygon{Sides: 6, Radius: 1, Center: Point{2, 0}}, true},
+		{"hexagon apart from rect", square, RegularPolygon{Sides: 6, Radius: 1, Center: Point{2.1, 0}}, false},
+		{"square polygon equal to rect", square, RegularPolygon{Sides: 4, Radius: math.Sqrt2, Angle: math.Pi / 4}, true},
+
+		{"triangle in notch of U", uShape, Triangle{Point{1.2, 2}, Point{1.8, 2}, Point{1.5, 2.8}}, false},
+		{"triangle across notch of U", uShape, Triangle{Point{0.5, 2}, Point{1.8, 2}, Point{1.5, 2.8}}, true},
+		{"triangle touching bottom of notch", uShape, Triangle{Point{1.2, 2}, Point{1.8, 2}, Point{1.5, 1}}, true},
+		{"triangle in base of U", uShape, Triangle{Point{0.2, 0.2}, Point{0.8, 0.2}, Point{0.5, 0.8}}, true},
+		{"U in big polygon", Polygon{[]Point{{-1, -1}, {4, -1}, {4, 4}, {1.5, 5}, {-1, 4}}}, uShape, true},
+		{"circle in notch of U", uShape, circle{radius: 0.4, center: Point{1.5, 2}}, false},
+		{"circle touching notch of U", uShape, circle{radius: 0.5, center: Point{1.5, 2}}, true},
+
+		{"segment across rect", square, LineSegment{Point{-2, 0}, Point{2, 0}}, true},
+		{"segment in rect", square, LineSegment{Point{-0.5, 0}, Point{0.5, 0}}, true},
+		{"segment along rect edge", square, LineSegment{Point{-2, 1}, Point{2, 1}}, true},
+		{"segment apart from rect", square, LineSegment{Point{-2, 1.1}, Point{2, 1.1}}, false},
+		{"segment tangent to circle", circle{radius: 1}, LineSegment{Point{-2, 1}, Point{2, 1}}, true},
+		{"segment in circle", circle{radius: 1}, LineSegment{Point{-0.5, 0}, Point{0.5, 0}}, true},
+		{"segment apart from circle", circle{radius: 1}, LineSegment{Point{-2, 1.1}, Point{2, 1.1}}, false},
+		{"segment in ellipse", Ellipse{A: 2, B: 1, Angle: 0.3}, LineSegment{Point{-0.5, 0}, Point{0.5, 0}}, true},
+		{"segment apart from ellipse", Ellipse{A: 2, B: 1}, LineSegment{Point{2.1, -1}, Point{2.1, 1}}, false},
+		{"segments crossing", LineSegment{Point{-1, -1}, Point{1, 1}}, LineSegment{Point{-1, 1}, Point{1, -1}}, true},
+		{"segments touching ends", LineSegment{Point{0, 0}, Point{1, 1}}, LineSegment{Point{1, 1}, Point{2, 0}}, true},
+		{"parallel segments", LineSegment{Point{0, 0}, Point{1, 0}}, LineSegment{Point{0, 0.1}, Point{1, 0.1}}, false},
+		{"collinear segments apart", LineSegment{Point{0, 0}, Point{1, 0}}, LineSegment{Point{1.1, 0}, Point{2, 0}}, false},
+		{"point segment on rect", square, LineSegment{Point{1, 0}, Point{1, 0}}, true},
+
+		{"composite with a part touching", square, Composite{[]Shape{circle{radius: 1, center: Point{5, 0}}, rect{width: 2, height: 2, center: Point{2, 0}}}}, true},
+		{"composite apart", square, Composite{[]Shape{circle{radius: 1, center: Point{5, 0}}, Triangle{Point{3, 0}, Point{4, 0}, Point{3, 1}}}}, false},
+		{"composites", Composite{[]Shape{square}}, Composite{[]Shape{circle{radius: 0.5}}}, true},
+		{"empty composite", square, Composite{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Intersects(tt.b); got != tt.want {
+			t.Errorf("%s: %T.Intersects(%T) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Intersects(tt.a); got != tt.want {
+			t.Errorf("%s: %T.Intersects(%T) = %v, want %v", tt.name, tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestDegenerateShapes(t *testing.T) {
+	others := []Shape{
+		square,
+		circle{radius: 1},
+		Ellipse{A: 2, B: 1},
+		Triangle{Point{-1, -1}, Point{1, -1}, Point{0, 1}},
+		RegularPolygon{Sides: 5, Radius: 1},
+		uShape,
+		LineSegment{Point{-1, 0}, Point{1, 0}},
+		Composite{[]Shape{square}},
+	}
+	others = append(others, degenerates...)
+	for _, d := range degenerates {
+		name := fmt.Sprintf("%T%+v", d, d)
+		if d.Contains(Point{}) {
+			t.Errorf("%s contains the origin", name)
+		}
+		for _, o := range others {
+			if d.Intersects(o) || o.Intersects(d) {
+				t.Errorf("%s intersects %T%+v", name, o, o)
+			}
+		}
+		if area, ok := IntersectionArea(d, square); ok && area != 0 {
+			t.Errorf("%s shares area %v with a rect", name, area)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		s    Shape
+		p    Point
+		want bool
+	}{
+		{square, Point{0, 0}, true},
+		{square, Point{1, 1}, true},
+		{square, Point{1, 0.5}, true},
+		{square, Point{1.01, 0}, false},
+		{rect{width: 2, height: 2, angle: math.Pi / 4}, Point{math.Sqrt2, 0}, true},
+		{rect{width: 2, height: 2, angle: math.Pi / 4}, Point{1, 1}, false},
+		{circle{radius: 1}, Point{0, 1}, true},
+		{circle{radius: 1}, Point{0.8, 0.8}, false},
+		{Ellipse{A: 2, B: 1}, Point{2, 0}, true},
+		{Ellipse{A: 2, B: 1}, Point{0, 1.01}, false},
+		{Ellipse{A: 2, B: 1, Angle: math.Pi / 2}, Point{0, 2}, true},
+		{Ellipse{A: 2, B: 1, Angle: math.Pi / 2}, Point{2, 0}, false},
+		{Triangle{Point{0, 0}, Point{2, 0}, Point{0, 2}}, Point{1, 1}, true},
+		{Triangle{Point{0, 0}, Point{2, 0}, Point{0, 2}}, Point{1.01, 1}, false},
+		{RegularPolygon{Sides: 6, Radius: 1}, Point{1, 0}, true},
+		{RegularPolygon{Sides: 6, Radius: 1}, Point{0, 0.9}, false},
+		{uShape, Point{0.5, 2}, true},
+		{uShape, Point{1.5, 2}, false},
+		{uShape, Point{1.5, 1}, true},
+		{LineSegment{Point{0, 0}, Point{2, 2}}, Point{1, 1}, true},
+		{LineSegment{Point{0, 0}, Point{2, 2}}, Point{3, 3}, false},
+		{Composite{[]Shape{square, circle{radius: 1, center: Point{5, 0}}}}, Point{5.5, 0}, true},
+		{Composite{[]Shape{square, circle{radius: 1, center: Point{5, 0}}}}, Point{3, 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Contains(tt.p); got != tt.want {
+			t.Errorf("%T%+v contains %v = %v, want %v", tt.s, tt.s, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestIntersectionArea(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Shape
+		want float64
+	}{
+		{"overlapping rects", square, rect{width: 2, height: 2, center: Point{1, 0}}, 2},
+		{"touching rects", square, rect{width: 2, height: 2, center: Point{2, 0}}, 0},
+		{"apart rects", square, rect{width: 2, height: 2, center: Point{3, 0}}, 0},
+		{"nested rects", square, rect{width: 1, height: 1}, 1},
+		{"rect and itself rotated", square, rect{width: 2, height: 2, angle: math.Pi / 4}, 8 * (math.Sqrt2 - 1)},
+		{"nested circles", circle{radius: 1}, circle{radius: 0.5, center: Point{0.2, 0}}, math.Pi / 4},
+		{"touching circles", circle{radius: 1}, circle{radius: 1, center: Point{2, 0}}, 0},
+		{"equal circles", circle{radius: 1}, circle{radius: 1}, math.Pi},
+		{"half circles", circle{radius: 1}, circle{radius: 1, center: Point{1, 0}}, 2*math.Pi/3 - math.Sqrt(3)/2},
+		{"circle in rect", square, circle{radius: 0.5}, math.Pi / 4},
+		{"rect in circle", circle{radius: 1}, rect{width: 1, height: 1}, 1},
+		{"circle on rect edge", square, circle{radius: 1, center: Point{1, 0}}, math.Pi / 2},
+		{"circle on rect corner", square, circle{radius: 0.5, center: Point{1, 1}}, math.Pi / 16},
+		{"circle touching rect", square, circle{radius: 1, center: Point{2, 0}}, 0},
+	}
+	for _, tt := range tests {
+		for _, pair := range [][2]Shape{{tt.a, tt.b}, {tt.b, tt.a}} {
+			got, ok := IntersectionArea(pair[0], pair[1])
+			if !ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("%s: IntersectionArea(%T, %T) = %v, %v, want %v", tt.name, pair[0], pair[1], got, ok, tt.want)
+			}
+		}
+	}
+
+	unsupported := []Shape{Ellipse{A: 2, B: 1}, Triangle{}, RegularPolygon{Sides: 5, Radius: 1}, uShape, LineSegment{}, Composite{}}
+	for _, s := range unsupported {
+		if _, ok := IntersectionArea(square, s); ok {
+			t.Errorf("IntersectionArea supports %T", s)
+		}
+	}
+}
@@ -1,11 +1,27 @@
 package examples
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"bitbucket.org/feliposz/go-by-example/counters"
+	"bitbucket.org/feliposz/go-by-example/events"
+	"bitbucket.org/feliposz/go-by-example/future"
+	"bitbucket.org/feliposz/go-by-example/group"
+	"bitbucket.org/feliposz/go-by-example/jobqueue"
+	"bitbucket.org/feliposz/go-by-example/lifecycle"
+	"bitbucket.org/feliposz/go-by-example/mux"
+	"bitbucket.org/feliposz/go-by-example/pipeline"
+	"bitbucket.org/feliposz/go-by-example/pubsub"
+	"bitbucket.org/feliposz/go-by-example/resilience"
+	"bitbucket.org/feliposz/go-by-example/schedule"
+	"bitbucket.org/feliposz/go-by-example/semaphore"
 )
 
 func goRoutineExample() {
@@ -31,10 +47,23 @@ func goRoutineExample() {
 	fmt.Scanln()
 }
 
+// MaxConcurrency limits how many goroutines multipleExample runs at once
+// (0 means no limit)
+var MaxConcurrency int
+
 func multipleExample() {
 	// Starts several concurrent routines
 	fmt.Println("<multiple>")
 	letters := "ABCDEFGHIJ"
+	if MaxConcurrency > 0 {
+		// Same 100 prints, but at most MaxConcurrency in flight
+		semaphore.ForEach(context.Background(), 10*len(letters), MaxConcurrency, func(ctx context.Context, n int) error {
+			fmt.Printf("%c%d ", letters[n%len(letters)], n/len(letters))
+			return nil
+		})
+		fmt.Println()
+		return
+	}
 	for i := 0; i < 10; i++ {
 		go func(i int) {
 			for _, c := range letters {
@@ -76,21 +105,23 @@ func bufferedExample() {
 
 func synchronizationExample() {
 
-	worker := func(done chan bool) {
+	worker := func(ctx context.Context) error {
 		fmt.Println("working...")
 		time.Sleep(time.Second)
 		fmt.Println("done")
-		// Tell channel work is done
-		done <- true
+		return nil
 	}
 
-	done := make(chan bool, 1)
+	// The group replaces the done channel
+	var g group.Group
 
 	// Call function asynchronously
-	go worker(done)
+	g.Go(worker)
 
-	// Wait for signal on channel
-	<-done
+	// Wait for it to return
+	if err := g.Wait(); err != nil {
+		fmt.Println("worker failed:", err)
+	}
 }
 
 func channelDirectionsExample() {
@@ -123,37 +154,42 @@ func deadlockExample() {
 
 func selectExample() {
 
-	c1 := make(chan string)
-	c2 := make(chan string)
-	c3 := make(chan string)
-
-	go func() {
-		time.Sleep(5 * time.Second)
-		c1 <- "one"
-	}()
+	// A select statement needs one case per channel, the mux takes any number
+	m := mux.New[string]()
 
-	go func() {
-		time.Sleep(3 * time.Second)
-		c2 <- "two"
-	}()
+	const sources = 5
+	for i := 1; i <= sources; i++ {
+		c := make(chan string)
+		go func(i int) {
+			// the last source sends first
+			time.Sleep(time.Duration(sources-i+1) * time.Second)
+			c <- fmt.Sprint("message from ", i)
+			close(c)
+		}(i)
+		m.Add(fmt.Sprint("c", i), c)
+	}
 
-	go func() {
-		time.Sleep(1 * time.Second)
-		c2 <- "three"
-	}()
+	// Like a select with a default case
+	if _, err := m.TryRecv(); err != nil {
+		fmt.Println("nothing yet:", err)
+	}
 
-	for i := 3; i > 0; i-- {
-		fmt.Println("Waiting on", i)
-		select {
-		case msg1 := <-c1:
-			fmt.Println("received", msg1)
-		case msg2 := <-c2:
-			fmt.Println("received", msg2)
-		case msg3 := <-c3:
-			fmt.Println("received", msg3)
+	// Closed channels are dropped, so this ends once every source is done
+	for m.Len() > 0 {
+		fmt.Println("Waiting on", m.Len())
+		msg, err := m.RecvTimeout(1500 * time.Millisecond)
+		switch err {
+		case nil:
+			fmt.Println("received", msg.Value, "on", msg.Label)
+		case mux.ErrTimeout:
+			fmt.Println("timeout")
+		case mux.ErrNoSources:
 		}
 	}
 
+	for _, st := range m.Stats() {
+		fmt.Printf("%s: %d (%.0f%%)\n", st.Label, st.Received, st.Share*100)
+	}
 }
 
 func timeoutExample() {
@@ -185,6 +221,104 @@ func timeoutExample() {
 
 }
 
+func futureExample() {
+	slow := func(d time.Duration, msg string) func(context.Context) (string, error) {
+		return func(ctx context.Context) (string, error) {
+			time.Sleep(d)
+			return msg, nil
+		}
+	}
+	ctx := context.Background()
+
+	// channelExample as a one-liner
+	msg, _ := future.Async(ctx, slow(2*time.Second, "done")).Await(ctx)
+	fmt.Println(msg)
+
+	// timeoutExample: the first wait times out, the second doesn't
+	if _, err := future.Async(ctx, slow(2*time.Second, "result 1")).AwaitTimeout(time.Second); err != nil {
+		fmt.Println("timeout 1:", err)
+	}
+	res, _ := future.Async(ctx, slow(2*time.Second, "result 2")).AwaitTimeout(3 * time.Second)
+	fmt.Println(res)
+
+	// Chaining and combinators
+	length := future.Map(future.Async(ctx, slow(time.Second, "gopher")), func(s string) int {
+		return len(s)
+	})
+	n, _ := length.Await(ctx)
+	fmt.Println("length:", n)
+
+	all, _ := future.All(ctx,
+		future.Async(ctx, slow(300*time.Millisecond, "a")),
+		future.Async(ctx, slow(100*time.Millisecond, "b"))).Await(ctx)
+	fmt.Println("all:", all)
+
+	first, _ := future.Race(ctx,
+		future.Async(ctx, slow(300*time.Millisecond, "slow")),
+		future.Async(ctx, slow(100*time.Millisecond, "fast"))).Await(ctx)
+	fmt.Println("race:", first)
+
+	// Panics become errors
+	_, err := future.Async(ctx, func(ctx context.Context) (int, error) {
+		var m map[string]int
+		m["boom"]++
+		return 0, nil
+	}).Await(ctx)
+	var perr *group.PanicError
+	if errors.As(err, &perr) {
+		fmt.Println("recovered:", perr.Value)
+	}
+}
+
+func retryExample() {
+	// A flaky dependency: only every third call answers in time
+	calls := 0
+	flaky := func(ctx context.Context) error {
+		calls++
+		n := calls
+		c := make(chan string, 1)
+		go func() {
+			if n%3 != 0 {
+				time.Sleep(2 * time.Second)
+			}
+			c <- fmt.Sprint("result ", n)
+		}()
+
+		select {
+		case res := <-c:
+			fmt.Println(res)
+			return nil
+		case <-time.After(500 * time.Millisecond):
+			return fmt.Errorf("timeout %d", n)
+		}
+	}
+
+	err := resilience.Retry(context.Background(), flaky, resilience.Policy{
+		Backoff:     resilience.Exponential(100*time.Millisecond, time.Second, 2),
+		MaxAttempts: 5,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			fmt.Println("attempt", attempt, "failed:", err, "- retrying in", wait)
+		},
+	})
+	fmt.Println("retry result:", err)
+
+	// Stop calling after two failures out of the last calls
+	breaker := resilience.NewCircuitBreaker(
+		resilience.FailureRate(0.5, 2),
+		resilience.OpenTimeout(time.Second),
+		resilience.OnStateChange(func(from, to resilience.State) {
+			fmt.Println("breaker", from, "->", to)
+		}))
+	for i := 0; i < 6; i++ {
+		err := breaker.Execute(func() error {
+			return flaky(context.Background())
+		})
+		if err != nil {
+			fmt.Println("call", i, "failed:", err)
+		}
+	}
+}
+
 func nonBlockingExample() {
 	messages := make(chan string)
 	signals := make(chan bool)
@@ -216,35 +350,72 @@ func nonBlockingExample() {
 
 func botChatExample() {
 
-	talk := make(chan string, 1)
-	done := make(chan bool, 1)
+	broker := pubsub.NewBroker[string]()
+
+	// Every bot talks on its own topic, the listener hears all of them
+	talk, err := broker.Subscribe("chat.*", 1, pubsub.Block)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	done, err := broker.Subscribe("done.*", 1, pubsub.Block)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	end := make(chan bool, 1)
 
-	go func() {
-		for i := 0; i < 5; i++ {
-			talk <- fmt.Sprint("hey", i)
-			time.Sleep(time.Second)
-		}
-		done <- true
-	}()
+	bots := []string{"alice", "bob", "carol"}
+	for _, bot := range bots {
+		go func(bot string) {
+			for i := 0; i < 5; i++ {
+				broker.Publish("chat."+bot, fmt.Sprint("hey", i))
+				time.Sleep(time.Second)
+			}
+			broker.Publish("done."+bot, "bye")
+		}(bot)
+	}
 
 	go func() {
-		finish := false
-		for !finish {
+		finished := 0
+		for finished < len(bots) {
 			select {
-			case msg := <-talk:
-				fmt.Println("listened: ", msg)
-			case <-done:
-				finish = true
-			default:
-				fmt.Println("waiting...")
-				time.Sleep(333 * time.Millisecond)
+			case msg := <-talk.C:
+				fmt.Println("listened: ", msg.Topic, msg.Payload)
+			case msg := <-done.C:
+				fmt.Println(msg.Topic, "said", msg.Payload)
+				finished++
 			}
 		}
 		fmt.Println("bye")
 		end <- true
 	}()
 	<-end
+
+	broker.Close()
+	fmt.Printf("stats: %+v\n", broker.Stats())
+}
+
+func debounceExample() {
+	// Keystrokes arrive in bursts, a search should only run once typing stops
+	keys := make(chan string)
+	go func() {
+		for _, word := range []string{"go", "gopher"} {
+			typed := ""
+			for _, c := range word {
+				typed += string(c)
+				keys <- typed
+				time.Sleep(100 * time.Millisecond)
+			}
+			time.Sleep(time.Second)
+		}
+		close(keys)
+	}()
+
+	ctx := context.Background()
+	for query := range events.Debounce(ctx, keys, 500*time.Millisecond) {
+		fmt.Println("searching for", query)
+	}
 }
 
 func closingExample() {
@@ -274,6 +445,34 @@ func closingExample() {
 	<-done
 }
 
+func pipelineExample() {
+	// Same as closingExample, but the channels are managed by the pipeline
+	p := pipeline.New(context.Background())
+
+	jobs := pipeline.Source(p, func(ctx context.Context, emit func(int) bool) error {
+		for j := 1; j <= 3; j++ {
+			if !emit(j) {
+				return ctx.Err()
+			}
+			fmt.Println("sent job", j)
+		}
+		fmt.Println("sent all jobs")
+		return nil
+	}, pipeline.Buffer(5))
+
+	pipeline.Sink(p, jobs, func(ctx context.Context, j int) error {
+		fmt.Println("received job", j)
+		return nil
+	})
+
+	// Source closes jobs when done, so Wait returns after the last job
+	if err := p.Wait(); err != nil {
+		fmt.Println("pipeline failed:", err)
+		return
+	}
+	fmt.Println("received all jobs")
+}
+
 func rangeChannelExample() {
 	queue := make(chan string, 3)
 
@@ -333,14 +532,45 @@ func tickerExample() {
 	fmt.Println("Ticker stopped")
 }
 
+func schedulerExample() {
+	s := schedule.New()
+
+	// 6 fields: every 2 seconds (a 5 field expression has minute resolution)
+	_, err := s.Cron("*/2 * * * * *", func(ctx context.Context) {
+		fmt.Println("Cron at", time.Now())
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Starts every 500ms, but a run is skipped while the previous is busy
+	s.FixedRate(500*time.Millisecond, func(ctx context.Context) {
+		fmt.Println("Slow job at", time.Now())
+		time.Sleep(700 * time.Millisecond)
+	})
+
+	s.Start()
+	time.Sleep(5 * time.Second)
+	s.Stop()
+	fmt.Println("Scheduler stopped")
+}
+
 func workerPoolExample() {
 
-	worker := func(id int, jobs <-chan int, results chan<- int) {
-		for j := range jobs {
-			fmt.Println("worker", id, "started job", j)
-			time.Sleep(time.Second)
-			fmt.Println("worker", id, "finished job", j)
-			results <- j * 2
+	worker := func(id int, jobs <-chan int, results chan<- int) func(context.Context) error {
+		return func(ctx context.Context) error {
+			for j := range jobs {
+				fmt.Println("worker", id, "started job", j)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				fmt.Println("worker", id, "finished job", j)
+				results <- j * 2
+			}
+			return nil
 		}
 	}
 
@@ -351,18 +581,84 @@ func workerPoolExample() {
 	const numJobs = 10
 
 	// Start workers
+	g, _ := group.WithContext(context.Background())
 	for w := 1; w <= numWorkers; w++ {
-		go worker(w, jobs, results)
+		g.Go(worker(w, jobs, results))
 	}
 
 	// Place jobs
 	for j := 1; j <= numJobs; j++ {
 		jobs <- j
 	}
+	close(jobs)
 
-	// Collect results
-	for r := 1; r <= numJobs; r++ {
-		<-results
+	// Workers return once jobs is drained, no need to count the results
+	if err := g.Wait(); err != nil {
+		fmt.Println("workers failed:", err)
+	}
+	close(results)
+	sum := 0
+	for r := range results {
+		sum += r
+	}
+	fmt.Println("sum of results:", sum)
+}
+
+// Unlike workerPoolExample, a job taken by a worker that dies is not lost:
+// it is delivered again once its visibility timeout expires
+func jobQueueExample() {
+	q, err := jobqueue.New[int](jobqueue.Visibility(300*time.Millisecond), jobqueue.MaxAttempts(3))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for j := 1; j <= 8; j++ {
+		q.Enqueue(j)
+	}
+	q.EnqueueDelayed(100, 500*time.Millisecond)
+
+	var sum int64
+	worker := func(id int) func(context.Context) error {
+		return func(ctx context.Context) error {
+			for {
+				d, err := q.Receive(ctx)
+				if err != nil {
+					return nil
+				}
+				fmt.Println("worker", id, "got job", d.Payload, "attempt", d.Attempt)
+				time.Sleep(50 * time.Millisecond)
+				switch {
+				case d.Payload == 3 && d.Attempt == 1:
+					fmt.Println("worker", id, "crashed")
+					return nil
+				case d.Payload == 7:
+					d.Nack(errors.New("poison job"), 0)
+				default:
+					atomic.AddInt64(&sum, int64(d.Payload*2))
+					d.Ack()
+				}
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g, _ := group.WithContext(ctx)
+	for w := 1; w <= 3; w++ {
+		g.Go(worker(w))
+	}
+	for {
+		s := q.Stats()
+		if s.Ready+s.Delayed+s.InFlight == 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	g.Wait()
+
+	fmt.Println("sum of results:", atomic.LoadInt64(&sum))
+	for _, job := range q.DeadLetters() {
+		fmt.Println("dead letter:", job.ID, job.Payload, "after", job.Attempts, "attempts:", job.LastError)
 	}
 }
 
@@ -433,7 +729,19 @@ func atomicExample() {
 	opsNonAtomicFinal := opsNonAtomic
 	fmt.Println("opsAtomic (exact):", opsAtomicFinal)
 	fmt.Println("opsNonAtomic (unsafe):", opsNonAtomicFinal)
+	// The counts were read while the goroutines are still running, so this is
+	// only an approximation of the increments lost by the unsafe counter
+	if opsAtomicFinal > opsNonAtomicFinal {
+		fmt.Println("lost updates (approx):", opsAtomicFinal-opsNonAtomicFinal)
+	}
+}
 
+func counterComparisonExample() {
+	// Same workload on each kind of counter, without sleeping between updates.
+	// Under "go run -race" the plain increment counter is reported as a data
+	// race, which is the point of including it.
+	results := counters.Compare(counters.All(true), 50, 20000)
+	counters.PrintResults(os.Stdout, results)
 }
 
 func mutexExample() {
@@ -447,44 +755,77 @@ func mutexExample() {
 	// Counters (updated atomically)
 	var readOps, writeOps uint64
 
-	// Start 100 readers and safely read from the state by locking a mutex
-	for r := 0; r < 100; r++ {
-		go func() {
-			total := 0
-			for {
-				key := rand.Intn(5)
+	// worker starts n goroutines calling op every millisecond until stopped
+	worker := func(name string, n int, op func()) lifecycle.Component {
+		quit := make(chan struct{})
+		var wg sync.WaitGroup
+		return lifecycle.Component{
+			Name: name,
+			Start: func(ctx context.Context) error {
+				wg.Add(n)
+				for i := 0; i < n; i++ {
+					go func() {
+						defer wg.Done()
+						for {
+							select {
+							case <-quit:
+								return
+							case <-time.After(time.Millisecond):
+								op()
+							}
+						}
+					}()
+				}
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				close(quit)
+				wg.Wait()
+				fmt.Println(name, "stopped")
+				return nil
+			},
+		}
+	}
 
-				mutex.Lock()
-				total += state[key]
-				mutex.Unlock()
+	// 10 writers safely write to the state by locking using a mutex
+	writers := worker("writers", 10, func() {
+		key := rand.Intn(5)
+		val := rand.Intn(100)
 
-				atomic.AddUint64(&readOps, 1)
+		mutex.Lock()
+		state[key] = val
+		mutex.Unlock()
 
-				time.Sleep(time.Millisecond)
-			}
-		}()
-	}
+		atomic.AddUint64(&writeOps, 1)
+	})
 
-	// Start 10 writers and safely write to the state by locking using a mutex
-	for w := 0; w < 10; w++ {
-		go func() {
-			for {
-				key := rand.Intn(5)
-				val := rand.Intn(100)
+	// 100 readers safely read from the state by locking a mutex. They depend
+	// on the writers, so they start after them and stop before them.
+	readers := worker("readers", 100, func() {
+		key := rand.Intn(5)
 
-				mutex.Lock()
-				state[key] = val
-				mutex.Unlock()
+		mutex.Lock()
+		_ = state[key]
+		mutex.Unlock()
 
-				atomic.AddUint64(&writeOps, 1)
+		atomic.AddUint64(&readOps, 1)
+	})
+	readers.DependsOn = []string{"writers"}
 
-				time.Sleep(time.Millisecond)
-			}
-		}()
+	m := lifecycle.New(2 * time.Second)
+	for _, c := range []lifecycle.Component{writers, readers} {
+		if err := m.Register(c); err != nil {
+			fmt.Println(err)
+			return
+		}
 	}
 
-	// Let readers and writers work
-	time.Sleep(time.Second)
+	// Let readers and writers work for a whole second (or until Ctrl-C)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Run(ctx); err != nil {
+		fmt.Println(err)
+	}
 
 	// Get updated counters
 	fmt.Println("Reads: ", atomic.LoadUint64(&readOps))
@@ -590,16 +931,27 @@ func ConcurrentExamples() {
 		selectExample()
 		// deadlockExample()
 		timeoutExample()
+		futureExample()
+		retryExample()
 		nonBlockingExample()
 		botChatExample()
+		debounceExample()
 		closingExample()
+		pipelineExample()
 		rangeChannelExample()
 		timerExample()
 		tickerExample()
+		schedulerExample()
 		workerPoolExample()
+		jobQueueExample()
 		rateLimitExample()
 		atomicExample()
+		counterComparisonExample()
 		mutexExample()
 	*/
+	// Without a limit multipleExample waits for <enter>, bounded it doesn't
+	if MaxConcurrency > 0 {
+		multipleExample()
+	}
 	statefulExample()
 }
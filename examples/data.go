@@ -1,9 +1,15 @@
 package examples
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sort"
+
+	"bitbucket.org/feliposz/go-by-example/errs"
+	"bitbucket.org/feliposz/go-by-example/group"
 )
 
 func sortingExample() {
@@ -48,6 +54,48 @@ func recoverExample() {
 	}
 }
 
+func safeCallExample() {
+
+	// The panic becomes an error holding the value and where it happened
+	err := errs.SafeCall(func() error {
+		panic("Help, something is wrong!!!")
+	})
+	var perr *group.PanicError
+	if errors.As(err, &perr) {
+		fmt.Println("panic value:", perr.Value, "- stack of", len(perr.Stack), "bytes")
+	}
+
+	// Bugs like a nil dereference can be let through to crash as usual,
+	// here caught again only to keep the examples running
+	err = errs.SafeCall(func() error {
+		return errs.SafeCall(func() error {
+			var m *map[string]int
+			(*m)["x"] = 1
+			return nil
+		}, errs.RepanicRuntimeErrors())
+	})
+	if errors.As(err, &perr) {
+		fmt.Println("runtime error passed through:", perr.Value)
+	}
+
+	done := make(chan error)
+	errs.SafeGo(func() {
+		panic("goroutine gave up")
+	}, func(err error) {
+		done <- err
+	})
+	if errors.As(<-done, &perr) {
+		fmt.Println("from goroutine:", perr.Value)
+	}
+
+	handler := errs.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler exploded")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/boom", nil))
+	fmt.Print("middleware answered ", rec.Code, ": ", rec.Body.String())
+}
+
 func deferExample() {
 	const filename = "tmp_file"
 
@@ -90,5 +138,6 @@ func DataExamples() {
 	sortingExample()
 	customSortExample()
 	panicExample()
+	safeCallExample()
 	deferExample()
 }
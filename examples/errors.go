@@ -1,36 +1,35 @@
 package examples
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sync"
+
+	"bitbucket.org/feliposz/go-by-example/errs"
 )
 
 func simpleErrorExample() {
 
 	failTest := func() (string, error) {
-		return "some value", errors.New("Simple error")
+		return "some value", errs.WithStack(errors.New(errs.Translate(errs.Locale(), "simple_error", nil)))
 	}
 
 	result, err := failTest()
 	if err != nil {
 		fmt.Println(err)
+		if frames := errs.StackTrace(err); len(frames) > 0 {
+			fmt.Println("created in", frames[0].Function)
+		}
 	}
 	fmt.Println(result)
 }
 
-type customError struct {
-	someErrorCode    int
-	someErrorMessage string
-}
-
-func (e *customError) Error() string {
-	return fmt.Sprintf("%d - %s", e.someErrorCode, e.someErrorMessage)
-}
-
 func customErrorExample() {
 
 	failTest := func() (string, error) {
-		return "some stuff", &customError{42, "don't panic"}
+		return "some stuff", errs.New(errs.DontPanic, "don't panic", "towel", false)
 	}
 
 	result, err := failTest()
@@ -38,6 +37,79 @@ func customErrorExample() {
 		fmt.Println(err)
 	}
 	fmt.Println(result)
+
+	// Branch on the kind of error, even when wrapped
+	wrapped := fmt.Errorf("hitchhiking: %w", err)
+	if errors.Is(wrapped, errs.ErrDontPanic) {
+		fmt.Println("matched by code:", errs.CodeOf(wrapped))
+	}
+	var e *errs.Error
+	if errors.As(wrapped, &e) {
+		towel, _ := e.Field("towel")
+		fmt.Println("code", int(e.Code), "message", e.Message, "towel", towel)
+	}
+
+	lookup := func(name string) error {
+		return errs.Wrap(os.ErrNotExist, errs.NotFound, "planet not found", "name", name)
+	}
+	err = lookup("Magrathea")
+	fmt.Println(err)
+	// %+v adds the stack where the error was created
+	fmt.Printf("%+v\n", err)
+	fmt.Println("not found:", errors.Is(err, errs.ErrNotFound), "os.ErrNotExist:", errors.Is(err, os.ErrNotExist))
+}
+
+func multiErrorExample() {
+
+	// Several goroutines fail, some for the same reason
+	var failures errs.MultiError
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch {
+			case i%5 == 0:
+				failures.Add(errs.New(errs.Unavailable, "backend down"))
+			case i%3 == 0:
+				failures.Add(fmt.Errorf("reader %d: %w", i, os.ErrPermission))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	err := failures.Err()
+	fmt.Println(err)
+	fmt.Println("unavailable:", errors.Is(err, errs.ErrUnavailable), "permission:", errors.Is(err, os.ErrPermission))
+	// errors.Join results are flattened and deduplicated too
+	fmt.Println(errs.Join(err, errors.Join(os.ErrPermission, os.ErrPermission)))
+}
+
+func errorMappingExample() {
+
+	failures := []error{
+		nil,
+		errs.New(errs.DontPanic, "don't panic"),
+		errs.New(errs.InvalidArgument, "bad planet name", "name", ""),
+		fmt.Errorf("loading: %w", context.DeadlineExceeded),
+		errors.New("something else"),
+	}
+	for _, err := range failures {
+		fmt.Printf("%-40v http %d, exit %d, grpc %v\n", err, errs.HTTPStatus(err), errs.ExitCode(err), errs.GRPCStatus(err))
+	}
+
+	fmt.Println(errs.DefaultRegistry.Problem(failures[2], "/planets/").JSON())
+}
+
+func localizedErrorExample() {
+
+	err := errs.NewKey(errs.NotFound, "planet_not_found", "name", "Magrathea")
+	err.Cause = errs.NewKey(errs.DontPanic, "dont_panic")
+	for _, lang := range []string{"en", "it", "pt-BR", "fr"} {
+		fmt.Printf("%-5s %s\n", lang, errs.Localize(err, lang))
+	}
+	// pt has no message for internal errors, so English is used
+	fmt.Println(errs.Localize(errs.NewKey(errs.Internal, "internal"), "pt"))
 }
 
 // ErrorExamples contains examples of error handling
@@ -47,4 +119,7 @@ func ErrorExamples() {
 
 	simpleErrorExample()
 	customErrorExample()
+	multiErrorExample()
+	errorMappingExample()
+	localizedErrorExample()
 }
@@ -0,0 +1,339 @@
+package examples
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// Drawing shapes to SVG and PNG
+
+// Style sets how a shape is drawn. A color with zero alpha is not drawn.
+type Style struct {
+	Fill        color.NRGBA
+	Stroke      color.NRGBA
+	StrokeWidth float64 // in pixels
+}
+
+// Renderer draws shapes scaled to fit the picture, with y growing upwards
+type Renderer struct {
+	Width, Height int
+	Margin        int
+	Background    color.NRGBA
+	// Styles are used in turn, shape i gets Styles[i%len(Styles)]
+	Styles []Style
+	// Labels adds area and perimeter to each shape (SVG only)
+	Labels bool
+}
+
+// curveSides is how finely circles and ellipses are drawn in a PNG
+const curveSides = 128
+
+// samples is the number of sub-rows per pixel row for anti-aliasing
+const samples = 4
+
+// NewRenderer creates a renderer with a white background and a palette of
+// translucent fills
+func NewRenderer(width, height int) *Renderer {
+	stroke := color.NRGBA{40, 40, 40, 255}
+	return &Renderer{
+		Width:      width,
+		Height:     height,
+		Margin:     10,
+		Background: color.NRGBA{255, 255, 255, 255},
+		Styles: []Style{
+			{Fill: color.NRGBA{31, 119, 180, 160}, Stroke: stroke, StrokeWidth: 1.5},
+			{Fill: color.NRGBA{255, 127, 14, 160}, Stroke: stroke, StrokeWidth: 1.5},
+			{Fill: color.NRGBA{44, 160, 44, 160}, Stroke: stroke, StrokeWidth: 1.5},
+			{Fill: color.NRGBA{214, 39, 40, 160}, Stroke: stroke, StrokeWidth: 1.5},
+			{Fill: color.NRGBA{148, 103, 189, 160}, Stroke: stroke, StrokeWidth: 1.5},
+		},
+	}
+}
+
+// place moves the shapes to pixel coordinates. Values that are not a Shape
+// have no position and are left out.
+func (r *Renderer) place(shapes []geometry) (placed []Shape, original []geometry) {
+	box := boxOf()
+	for _, g := range shapes {
+		if s, ok := g.(Shape); ok {
+			box = box.Union(s.BoundingBox())
+			original = append(original, g)
+		}
+	}
+	if box.Empty() {
+		return nil, nil
+	}
+	w, h := float64(r.Width-2*r.Margin), float64(r.Height-2*r.Margin)
+	scale := math.Inf(1)
+	if box.Width() > 0 {
+		scale = w / box.Width()
+	}
+	if box.Height() > 0 {
+		scale = math.Min(scale, h/box.Height())
+	}
+	if math.IsInf(scale, 1) {
+		scale = 1
+	}
+	cx, cy := (box.Min.X+box.Max.X)/2, (box.Min.Y+box.Max.Y)/2
+	toPixel := Translation(-cx, -cy).Then(Scaling(scale, -scale)).Then(Translation(float64(r.Width)/2, float64(r.Height)/2))
+	for _, g := range original {
+		placed = append(placed, g.(Shape).Transform(toPixel))
+	}
+	return placed, original
+}
+
+func (r *Renderer) style(i int) Style {
+	if len(r.Styles) == 0 {
+		return Style{Stroke: color.NRGBA{0, 0, 0, 255}, StrokeWidth: 1}
+	}
+	return r.Styles[i%len(r.Styles)]
+}
+
+// SVG writes the shapes as an SVG document
+func (r *Renderer) SVG(w io.Writer, shapes []geometry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", r.Width, r.Height, r.Width, r.Height)
+	if r.Background.A > 0 {
+		fmt.Fprintf(bw, `<rect width="100%%" height="100%%" %s/>`+"\n", svgPaint("fill", r.Background))
+	}
+	placed, original := r.place(shapes)
+	for i, s := range placed {
+		st := r.style(i)
+		fmt.Fprintf(bw, `<g %s %s stroke-width="%g">`+"\n", svgPaint("fill", st.Fill), svgPaint("stroke", st.Stroke), st.StrokeWidth)
+		writeSVGShape(bw, s)
+		bw.WriteString("</g>\n")
+	}
+	if r.Labels {
+		for i, s := range placed {
+			c := s.Centroid()
+			fmt.Fprintf(bw, `<text x="%.2f" y="%.2f" font-family="sans-serif" font-size="11" text-anchor="middle">A=%.2f P=%.2f</text>`+"\n",
+				c.X, c.Y, original[i].area(), original[i].perim())
+		}
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+func svgPaint(attr string, c color.NRGBA) string {
+	if c.A == 0 {
+		return attr + `="none"`
+	}
+	return fmt.Sprintf(`%s="rgb(%d,%d,%d)" %s-opacity="%.3f"`, attr, c.R, c.G, c.B, attr, float64(c.A)/255)
+}
+
+func writeSVGShape(w io.Writer, s Shape) {
+	switch s := s.(type) {
+	case circle:
+		fmt.Fprintf(w, `<circle cx="%.2f" cy="%.2f" r="%.2f"/>`+"\n", s.center.X, s.center.Y, s.radius)
+	case Ellipse:
+		fmt.Fprintf(w, `<ellipse cx="%.2f" cy="%.2f" rx="%.2f" ry="%.2f" transform="rotate(%.2f %.2f %.2f)"/>`+"\n",
+			s.Center.X, s.Center.Y, s.A, s.B, s.Angle*180/math.Pi, s.Center.X, s.Center.Y)
+	case LineSegment:
+		fmt.Fprintf(w, `<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f"/>`+"\n", s.A.X, s.A.Y, s.B.X, s.B.Y)
+	case Composite:
+		for _, child := range s.Shapes {
+			writeSVGShape(w, child)
+		}
+	default:
+		points, _ := outline(s)
+		io.WriteString(w, `<polygon points="`)
+		for i, p := range points {
+			if i > 0 {
+				io.WriteString(w, " ")
+			}
+			fmt.Fprintf(w, "%.2f,%.2f", p.X, p.Y)
+		}
+		io.WriteString(w, `"/>`+"\n")
+	}
+}
+
+// path is an outline to fill or stroke
+type path struct {
+	points []Point
+	closed bool
+}
+
+// paths returns the outlines of s, curves made of straight pieces
+func paths(s Shape) []path {
+	switch s := s.(type) {
+	case circle:
+		return paths(Ellipse{A: s.radius, B: s.radius, Center: s.center})
+	case Ellipse:
+		vs := make([]Point, curveSides)
+		m := Scaling(s.A, s.B).Then(Rotation(s.Angle)).Then(Translation(s.Center.X, s.Center.Y))
+		for i := range vs {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / curveSides)
+			vs[i] = m.Apply(Point{cos, sin})
+		}
+		return []path{{vs, true}}
+	case LineSegment:
+		return []path{{[]Point{s.A, s.B}, false}}
+	case Composite:
+		var list []path
+		for _, child := range s.Shapes {
+			list = append(list, paths(child)...)
+		}
+		return list
+	}
+	points, _ := outline(s)
+	return []path{{points, true}}
+}
+
+// strokePolygons turns the outline into quads along each piece and
+// octagons at each vertex, all counterclockwise so their union is filled
+func strokePolygons(p path, width float64) [][]Point {
+	hw := width / 2
+	var polys [][]Point
+	n := len(p.points)
+	pieces := n - 1
+	if p.closed {
+		pieces = n
+	}
+	for i := 0; i < pieces; i++ {
+		a, b := p.points[i], p.points[(i+1)%n]
+		d := sub(b, a)
+		l := math.Hypot(d.X, d.Y)
+		if l == 0 {
+			continue
+		}
+		nx, ny := -d.Y/l*hw, d.X/l*hw
+		polys = append(polys, []Point{{a.X - nx, a.Y - ny}, {b.X - nx, b.Y - ny}, {b.X + nx, b.Y + ny}, {a.X + nx, a.Y + ny}})
+	}
+	for _, v := range p.points {
+		oct := make([]Point, 8)
+		for i := range oct {
+			sin, cos := math.Sincos(math.Pi / 4 * float64(i))
+			oct[i] = Point{v.X + hw*cos, v.Y + hw*sin}
+		}
+		polys = append(polys, oct)
+	}
+	for i, poly := range polys {
+		if signedArea(poly) < 0 {
+			polys[i] = reversed(poly)
+		}
+	}
+	return polys
+}
+
+func signedArea(vertices []Point) float64 {
+	sum := 0.0
+	for i, a := range vertices {
+		sum += cross(a, vertices[(i+1)%len(vertices)])
+	}
+	return sum / 2
+}
+
+// coverage rasterizes polygons with the nonzero winding rule into a mask
+// whose alpha is the fraction of each pixel covered
+func coverage(bounds image.Rectangle, polys [][]Point) *image.Alpha {
+	type edge struct {
+		x0, y0, x1, y1 float64
+		dir            int
+	}
+	var edges []edge
+	for _, poly := range polys {
+		for i, a := range poly {
+			b := poly[(i+1)%len(poly)]
+			switch {
+			case a.Y < b.Y:
+				edges = append(edges, edge{a.X, a.Y, b.X, b.Y, 1})
+			case a.Y > b.Y:
+				edges = append(edges, edge{b.X, b.Y, a.X, a.Y, -1})
+			}
+		}
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	acc := make([]float64, w*h)
+	addSpan := func(row []float64, xa, xb float64) {
+		xa, xb = math.Max(xa, 0), math.Min(xb, float64(w))
+		if xa >= xb {
+			return
+		}
+		const weight = 1.0 / samples
+		ia, ib := int(xa), int(xb)
+		if ia == ib {
+			row[ia] += (xb - xa) * weight
+			return
+		}
+		row[ia] += (float64(ia+1) - xa) * weight
+		for i := ia + 1; i < ib; i++ {
+			row[i] += weight
+		}
+		if ib < w {
+			row[ib] += (xb - float64(ib)) * weight
+		}
+	}
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	var crossings []crossing
+	for py := 0; py < h; py++ {
+		row := acc[py*w : (py+1)*w]
+		for s := 0; s < samples; s++ {
+			y := float64(py) + (float64(s)+0.5)/samples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if e.y0 <= y && y < e.y1 {
+					crossings = append(crossings, crossing{e.x0 + (y-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding, start := 0, 0.0
+			for _, c := range crossings {
+				before := winding
+				winding += c.dir
+				if before == 0 && winding != 0 {
+					start = c.x
+				} else if before != 0 && winding == 0 {
+					addSpan(row, start, c.x)
+				}
+			}
+		}
+	}
+
+	mask := image.NewAlpha(bounds)
+	for i, v := range acc {
+		mask.Pix[i] = uint8(math.Round(math.Min(v, 1) * 255))
+	}
+	return mask
+}
+
+// Image draws the shapes, each fill and stroke blended over the previous
+// ones through an anti-aliased coverage mask
+func (r *Renderer) Image(shapes []geometry) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(r.Background), image.Point{}, draw.Src)
+	placed, _ := r.place(shapes)
+	for i, s := range placed {
+		st := r.style(i)
+		var fills, strokes [][]Point
+		for _, p := range paths(s) {
+			if p.closed && len(p.points) > 2 {
+				fills = append(fills, p.points)
+			}
+			strokes = append(strokes, strokePolygons(p, st.StrokeWidth)...)
+		}
+		if st.Fill.A > 0 && len(fills) > 0 {
+			draw.DrawMask(img, img.Bounds(), image.NewUniform(st.Fill), image.Point{}, coverage(img.Bounds(), fills), image.Point{}, draw.Over)
+		}
+		if st.Stroke.A > 0 && st.StrokeWidth > 0 {
+			draw.DrawMask(img, img.Bounds(), image.NewUniform(st.Stroke), image.Point{}, coverage(img.Bounds(), strokes), image.Point{}, draw.Over)
+		}
+	}
+	return img
+}
+
+// PNG writes the shapes as a PNG image
+func (r *Renderer) PNG(w io.Writer, shapes []geometry) error {
+	return png.Encode(w, r.Image(shapes))
+}
@@ -0,0 +1,121 @@
+package examples
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// maxChannelDiff absorbs small rounding differences between platforms
+const maxChannelDiff = 2
+
+func goldenShapes() []geometry {
+	return []geometry{
+		rect{width: 4, height: 2},
+		Rotate(rect{width: 4, height: 2, center: Point{5, 0}}, math.Pi/6),
+		circle{radius: 1.5, center: Point{1, 3}},
+		Scale(circle{radius: 1, center: Point{6, 3.5}}, 2, 1),
+		Triangle{Point{-3, -3}, Point{0, -3}, Point{-3, 0}},
+		RegularPolygon{Sides: 6, Radius: 1.2, Center: Point{3, -3}},
+		LineSegment{Point{-3, 4}, Point{8, -4}},
+		Composite{[]Shape{uShape}},
+	}
+}
+
+func goldenRenderer() *Renderer {
+	r := NewRenderer(240, 180)
+	r.Labels = true
+	return r
+}
+
+// golden returns the expected content of name, first writing got to it with
+// -update
+func golden(t *testing.T, name string, got []byte) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	return want
+}
+
+func TestSVGGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := goldenRenderer().SVG(&buf, goldenShapes()); err != nil {
+		t.Fatal(err)
+	}
+	if want := golden(t, "shapes.svg", buf.Bytes()); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("SVG differs from testdata/shapes.svg:\n%s", buf.Bytes())
+	}
+}
+
+func TestPNGGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := goldenRenderer().PNG(&buf, goldenShapes()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := png.Decode(bytes.NewReader(golden(t, "shapes.png", buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds %v, want %v", got.Bounds(), want.Bounds())
+	}
+	if n := differentPixels(got, want); n > 0 {
+		t.Errorf("%d pixels differ from testdata/shapes.png by more than %d", n, maxChannelDiff)
+	}
+}
+
+// differentPixels counts the pixels with a channel off by more than
+// maxChannelDiff
+func differentPixels(a, b image.Image) int {
+	n := 0
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			for _, d := range []int{int(r1) - int(r2), int(g1) - int(g2), int(b1) - int(b2), int(a1) - int(a2)} {
+				if d>>8 > maxChannelDiff || -d>>8 > maxChannelDiff {
+					n++
+					break
+				}
+			}
+		}
+	}
+	return n
+}
+
+func TestRenderEmpty(t *testing.T) {
+	r := goldenRenderer()
+	img := r.Image(nil)
+	if img.Bounds().Dx() != r.Width || img.Bounds().Dy() != r.Height {
+		t.Fatalf("bounds %v", img.Bounds())
+	}
+	// only the background, and non shapes are left out
+	for _, shapes := range [][]geometry{nil, {Polygon{}}} {
+		img := r.Image(shapes)
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != 255 || img.Pix[i+1] != 255 || img.Pix[i+2] != 255 || img.Pix[i+3] != 255 {
+				t.Fatalf("%v: pixel %d is not white", shapes, i/4)
+			}
+		}
+	}
+}
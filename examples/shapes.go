@@ -0,0 +1,136 @@
+package examples
+
+import "math"
+
+// More shapes satisfying geometry
+
+// Point is a position in the plane
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) dist(q Point) float64 {
+	return math.Hypot(q.X-p.X, q.Y-p.Y)
+}
+
+// Triangle is given by its three vertices
+type Triangle struct {
+	A, B, C Point
+}
+
+func (t Triangle) area() float64 {
+	return Polygon{[]Point{t.A, t.B, t.C}}.area()
+}
+
+func (t Triangle) perim() float64 {
+	return t.A.dist(t.B) + t.B.dist(t.C) + t.C.dist(t.A)
+}
+
+// Ellipse is given by its semi-axes, the first one turned by Angle radians
+type Ellipse struct {
+	A, B   float64
+	Center Point
+	Angle  float64
+}
+
+func (e Ellipse) area() float64 {
+	return math.Pi * e.A * e.B
+}
+
+// perim has no closed form, this is Ramanujan's second approximation
+func (e Ellipse) perim() float64 {
+	if e.A+e.B == 0 {
+		return 0
+	}
+	h := math.Pow(e.A-e.B, 2) / math.Pow(e.A+e.B, 2)
+	return math.Pi * (e.A + e.B) * (1 + 3*h/(10+math.Sqrt(4-3*h)))
+}
+
+// RegularPolygon has Sides equal sides and vertices at Radius from the
+// center. With fewer than 3 sides it is degenerate, without area or
+// perimeter.
+type RegularPolygon struct {
+	Sides  int
+	Radius float64
+	Center Point
+	Angle  float64
+}
+
+func (r RegularPolygon) area() float64 {
+	if r.Sides < 3 {
+		return 0
+	}
+	n := float64(r.Sides)
+	return n * r.Radius * r.Radius * math.Sin(2*math.Pi/n) / 2
+}
+
+func (r RegularPolygon) perim() float64 {
+	if r.Sides < 3 {
+		return 0
+	}
+	n := float64(r.Sides)
+	return 2 * n * r.Radius * math.Sin(math.Pi/n)
+}
+
+// Polygon is a simple polygon, its vertices in order, either direction
+type Polygon struct {
+	Vertices []Point
+}
+
+// area uses the shoelace formula
+func (p Polygon) area() float64 {
+	sum := 0.0
+	for i, a := range p.Vertices {
+		b := p.Vertices[(i+1)%len(p.Vertices)]
+		sum += a.X*b.Y - b.X*a.Y
+	}
+	return math.Abs(sum) / 2
+}
+
+func (p Polygon) perim() float64 {
+	sum := 0.0
+	for i, a := range p.Vertices {
+		sum += a.dist(p.Vertices[(i+1)%len(p.Vertices)])
+	}
+	return sum
+}
+
+// LineSegment has no area and its length as perimeter
+type LineSegment struct {
+	A, B Point
+}
+
+// Length of the segment
+func (l LineSegment) Length() float64 {
+	return l.A.dist(l.B)
+}
+
+func (l LineSegment) area() float64 {
+	return 0
+}
+
+func (l LineSegment) perim() float64 {
+	return l.Length()
+}
+
+// Composite groups shapes that don't overlap, so its area and perimeter
+// are the sums of theirs
+type Composite struct {
+	Shapes []Shape
+}
+
+func (c Composite) area() float64 {
+	sum := 0.0
+	for _, g := range c.Shapes {
+		sum += g.area()
+	}
+	return sum
+}
+
+func (c Composite) perim() float64 {
+	sum := 0.0
+	for _, g := range c.Shapes {
+		sum += g.perim()
+	}
+	return sum
+}
@@ -0,0 +1,96 @@
+package examples
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegularPolygonConvergesToCircle(t *testing.T) {
+	c := circle{radius: 2}
+	prevArea, prevPerim := 0.0, 0.0
+	for _, sides := range []int{3, 4, 6, 12, 48, 360, 10000} {
+		r := RegularPolygon{Sides: sides, Radius: c.radius}
+		area, perim := r.area(), r.perim()
+		// inscribed polygons grow towards the circle without passing it
+		if area <= prevArea || area > c.area() {
+			t.Errorf("%d sides: area %v not between %v and %v", sides, area, prevArea, c.area())
+		}
+		if perim <= prevPerim || perim > c.perim() {
+			t.Errorf("%d sides: perimeter %v not between %v and %v", sides, perim, prevPerim, c.perim())
+		}
+		prevArea, prevPerim = area, perim
+	}
+	if d := c.area() - prevArea; d > 1e-6 {
+		t.Errorf("area still %v away from the circle", d)
+	}
+	if d := c.perim() - prevPerim; d > 1e-6 {
+		t.Errorf("perimeter still %v away from the circle", d)
+	}
+}
+
+func TestRegularPolygonMatchesVertices(t *testing.T) {
+	for sides := 3; sides <= 12; sides++ {
+		r := RegularPolygon{Sides: sides, Radius: 1.5, Center: Point{1, -2}, Angle: 0.3}
+		p := Polygon{r.Vertices()}
+		if !ShapeTolerance.Equal(r.area(), p.area()) || !ShapeTolerance.Equal(r.perim(), p.perim()) {
+			t.Errorf("%d sides: area %v perimeter %v, vertices give %v and %v", sides, r.area(), r.perim(), p.area(), p.perim())
+		}
+	}
+}
+
+func TestDegenerateMeasures(t *testing.T) {
+	shapes := []geometry{
+		Ellipse{},
+		RegularPolygon{Sides: 0, Radius: 1},
+		RegularPolygon{Sides: 1, Radius: 1},
+		RegularPolygon{Sides: 2, Radius: 1},
+		RegularPolygon{Sides: -3, Radius: 1},
+		Polygon{},
+	}
+	for _, g := range shapes {
+		if a, p := g.area(), g.perim(); a != 0 || p != 0 {
+			t.Errorf("%T%+v: area %v, perimeter %v, want 0", g, g, a, p)
+		}
+	}
+	// the approximation is worst for a flat ellipse, 0.04% short
+	if p := (Ellipse{A: 1}).perim(); math.Abs(p-4) > 4e-3 {
+		t.Errorf("flat ellipse perimeter %v, want about 4", p)
+	}
+	if p := (Ellipse{A: 1, B: 1}).perim(); !ShapeTolerance.Equal(p, 2*math.Pi) {
+		t.Errorf("round ellipse perimeter %v, want 2π", p)
+	}
+}
+
+func TestAreaInvariantUnderMoves(t *testing.T) {
+	shapes := []Shape{
+		rect{width: 4, height: 2},
+		circle{radius: 1.5, center: Point{1, 3}},
+		Ellipse{A: 3, B: 1, Angle: 0.5},
+		Triangle{Point{0, 0}, Point{4, 0}, Point{1, 3}},
+		RegularPolygon{Sides: 7, Radius: 2, Center: Point{-1, 1}},
+		uShape,
+		LineSegment{Point{0, 0}, Point{3, 4}},
+		Composite{[]Shape{rect{width: 1, height: 1}, circle{radius: 1, center: Point{3, 0}}}},
+	}
+	moves := []struct {
+		name string
+		move func(Shape) Shape
+	}{
+		{"translated", func(s Shape) Shape { return Translate(s, 3.5, -7) }},
+		{"rotated", func(s Shape) Shape { return Rotate(s, math.Pi/5) }},
+		{"rotated a full turn", func(s Shape) Shape { return Rotate(s, 2*math.Pi) }},
+		{"rotated about the origin", func(s Shape) Shape { return s.Transform(Rotation(2)) }},
+		{"moved and rotated", func(s Shape) Shape { return Rotate(Translate(s, -2, 5), -1) }},
+	}
+	for _, s := range shapes {
+		for _, m := range moves {
+			moved := m.move(s)
+			if !ShapeTolerance.Equal(moved.area(), s.area()) {
+				t.Errorf("%T %s: area %v, was %v", s, m.name, moved.area(), s.area())
+			}
+			if !ShapeTolerance.Equal(moved.perim(), s.perim()) {
+				t.Errorf("%T %s: perimeter %v, was %v", s, m.name, moved.perim(), s.perim())
+			}
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package examples
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
 )
 
 // Struct
@@ -32,6 +36,8 @@ func methodExamples() {
 
 type rect struct {
 	width, height float64
+	center        Point
+	angle         float64
 }
 
 func (r rect) area() float64 {
@@ -42,9 +48,17 @@ func (r rect) perim() float64 {
 	return r.width*2 + r.height*2
 }
 
+// grow adds amount to both sides, scaling the rect along its own axes
+// around its center
 func (r *rect) grow(amount float64) {
-	r.width += amount
-	r.height += amount
+	if r.width == 0 || r.height == 0 {
+		// no factor turns a zero side into amount
+		r.width += amount
+		r.height += amount
+		return
+	}
+	local := Rotation(-r.angle).Then(Scaling((r.width+amount)/r.width, (r.height+amount)/r.height)).Then(Rotation(r.angle))
+	*r = r.Transform(local.About(r.center)).(rect)
 }
 
 // Interfaces
@@ -56,6 +70,7 @@ type geometry interface {
 
 type circle struct {
 	radius float64
+	center Point
 }
 
 func (c circle) area() float64 {
@@ -71,12 +86,131 @@ func describeGeometry(g geometry) {
 }
 
 func interfaceExamples() {
-	r := rect{10, 20}
-	c := circle{10}
+	r := rect{width: 10, height: 20}
+	c := circle{radius: 10}
 	describeGeometry(r)
 	describeGeometry(c)
 }
 
+func shapesExample() {
+	shapes := []geometry{
+		Triangle{Point{0, 0}, Point{4, 0}, Point{0, 3}},
+		Ellipse{A: 5, B: 3},
+		RegularPolygon{Sides: 6, Radius: 2},
+		Polygon{[]Point{{0, 0}, {4, 0}, {4, 4}, {2, 6}, {0, 4}}},
+		LineSegment{Point{0, 0}, Point{3, 4}},
+		Composite{[]Shape{rect{width: 2, height: 3}, circle{radius: 1}}},
+	}
+	for _, g := range shapes {
+		describeGeometry(g)
+	}
+
+	// With more sides a regular polygon gets closer to its circle
+	c := circle{radius: 1}
+	for _, n := range []int{3, 6, 12, 48, 192, 1000} {
+		p := RegularPolygon{Sides: n, Radius: 1}
+		fmt.Printf("%4d sides: area off by %.6f, perimeter off by %.6f\n", n, c.area()-p.area(), c.perim()-p.perim())
+	}
+}
+
+func transformExample() {
+	r := rect{width: 4, height: 2}
+	r.grow(2)
+	fmt.Println("grown rect:", r, "box:", r.BoundingBox())
+
+	shapes := []Shape{
+		r,
+		circle{radius: 1, center: Point{2, 2}},
+		Triangle{Point{0, 0}, Point{4, 0}, Point{0, 3}},
+		RegularPolygon{Sides: 5, Radius: 1},
+	}
+	for _, s := range shapes {
+		moved := Rotate(Translate(s, 3, 1), math.Pi/6)
+		fmt.Printf("%T centroid %.2f -> %.2f, area %.2f -> %.2f\n", s, s.Centroid(), moved.Centroid(), s.area(), moved.area())
+		fmt.Printf("  box %.2f, oriented box %.2f\n", moved.BoundingBox(), moved.OrientedBox())
+	}
+
+	// Stretching a circle or shearing a rect changes their type
+	fmt.Printf("%T\n", Scale(circle{radius: 1}, 2, 1))
+	fmt.Printf("%T\n", rect{width: 1, height: 1}.Transform(Affine{A: 1, B: 0.5, E: 1}))
+}
+
+func collisionExample() {
+	r := rect{width: 4, height: 2}
+	fmt.Println("rect contains (2, 1):", r.Contains(Point{2, 1}), "(2, 1.1):", r.Contains(Point{2, 1.1}))
+
+	// A U shape and triangles in and across its notch
+	u := Polygon{[]Point{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}}}
+	inNotch := Triangle{Point{1.2, 2}, Point{1.8, 2}, Point{1.5, 2.8}}
+	fmt.Println("U hits triangle in notch:", u.Intersects(inNotch), "moved down:", u.Intersects(Translate(inNotch, 0, -1.5)))
+
+	pairs := [][2]Shape{
+		{r, rect{width: 2, height: 4, center: Point{1, 1}, angle: 0.4}},
+		{circle{radius: 1}, circle{radius: 1.5, center: Point{1.2, 0.3}}},
+		{circle{radius: 1, center: Point{2, 1}}, r},
+		{Ellipse{A: 3, B: 1, Angle: math.Pi / 4}, LineSegment{Point{2, 0}, Point{3, 0}}},
+	}
+	for _, p := range pairs {
+		fmt.Printf("%T x %T: intersects %v", p[0], p[1], p[0].Intersects(p[1]))
+		if area, ok := IntersectionArea(p[0], p[1]); ok {
+			fmt.Printf(", shared area %.4f", area)
+		}
+		fmt.Println()
+	}
+}
+
+// RenderDir is where renderExample saves its pictures (empty means they are
+// not saved)
+var RenderDir string
+
+func renderExample() {
+	shapes := []geometry{
+		rect{width: 4, height: 2},
+		Rotate(rect{width: 4, height: 2, center: Point{5, 0}}, math.Pi/6),
+		circle{radius: 1.5, center: Point{1, 3}},
+		Scale(circle{radius: 1, center: Point{6, 3.5}}, 2, 1),
+		Triangle{Point{-3, -3}, Point{0, -3}, Point{-3, 0}},
+		RegularPolygon{Sides: 6, Radius: 1.2, Center: Point{3, -3}},
+		LineSegment{Point{-3, 4}, Point{8, -4}},
+	}
+
+	r := NewRenderer(480, 360)
+	r.Labels = true
+	if RenderDir == "" {
+		// Nothing to keep, only tell how large the pictures are
+		var svg, img bytes.Buffer
+		if err := r.SVG(&svg, shapes); err != nil {
+			fmt.Println(err)
+		}
+		if err := r.PNG(&img, shapes); err != nil {
+			fmt.Println(err)
+		}
+		fmt.Printf("drew %d shapes: %d bytes of SVG, %d bytes of PNG\n", len(shapes), svg.Len(), img.Len())
+		return
+	}
+
+	outputs := []struct {
+		name  string
+		write func(io.Writer, []geometry) error
+	}{
+		{"shapes.svg", r.SVG},
+		{"shapes.png", r.PNG},
+	}
+	for _, out := range outputs {
+		path := filepath.Join(RenderDir, out.name)
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if err := out.write(f, shapes); err != nil {
+			fmt.Println(err)
+		}
+		f.Close()
+		fmt.Println("drew", len(shapes), "shapes to", path)
+	}
+}
+
 // StructExamples contains examples of structs
 func StructExamples() {
 	fmt.Println("\nSome struct examples")
@@ -85,4 +219,8 @@ func StructExamples() {
 	structExample()
 	methodExamples()
 	interfaceExamples()
+	shapesExample()
+	transformExample()
+	collisionExample()
+	renderExample()
 }
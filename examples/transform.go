@@ -0,0 +1,513 @@
+package examples
+
+import (
+	"math"
+	"sort"
+)
+
+// Positioned shapes and affine transforms
+
+// Tolerance decides when two numbers are close enough to be equal,
+// absorbing floating point rounding. Within it, points on a border are
+// contained and shapes touching each other intersect.
+type Tolerance struct {
+	Abs float64 // for numbers near zero
+	Rel float64 // relative to the larger magnitude
+}
+
+// Equal tells whether a and b are within the tolerance
+func (t Tolerance) Equal(a, b float64) bool {
+	d := math.Abs(a - b)
+	return d <= t.Abs || d <= t.Rel*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// LessOrEqual is a <= b within the tolerance
+func (t Tolerance) LessOrEqual(a, b float64) bool {
+	return a <= b || t.Equal(a, b)
+}
+
+// ShapeTolerance is used by every shape computation
+var ShapeTolerance = Tolerance{Abs: 1e-9, Rel: 1e-9}
+
+// Shape is a geometry placed in the plane
+type Shape interface {
+	geometry
+	// Centroid is the center of mass of the shape
+	Centroid() Point
+	// Transform returns the shape moved by m. The result has the same type
+	// when it can describe the new shape, like a rect rotated by 30
+	// degrees, and a more general one otherwise, like the Polygon of a
+	// sheared rect.
+	Transform(m Affine) Shape
+	// BoundingBox is the smallest box with sides parallel to the axes
+	// containing the shape
+	BoundingBox() Box
+	// OrientedBox is a smallest box, in any direction, containing the shape
+	OrientedBox() OBB
+	// Contains tells whether p is inside the shape or on its border
+	Contains(p Point) bool
+	// Intersects tells whether the shapes have a point in common
+	Intersects(o Shape) bool
+}
+
+// Affine maps (x, y) to (A*x + B*y + C, D*x + E*y + F)
+type Affine struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// Identity leaves points where they are
+var Identity = Affine{A: 1, E: 1}
+
+// Translation moves points by dx, dy
+func Translation(dx, dy float64) Affine {
+	return Affine{A: 1, C: dx, E: 1, F: dy}
+}
+
+// Rotation turns points by theta radians, counterclockwise, around the
+// origin
+func Rotation(theta float64) Affine {
+	sin, cos := math.Sincos(theta)
+	return Affine{A: cos, B: -sin, D: sin, E: cos}
+}
+
+// Scaling stretches points away from the origin
+func Scaling(sx, sy float64) Affine {
+	return Affine{A: sx, E: sy}
+}
+
+// About applies m as if p was the origin
+func (m Affine) About(p Point) Affine {
+	return Translation(-p.X, -p.Y).Then(m).Then(Translation(p.X, p.Y))
+}
+
+// Then returns the transform applying m first and n after
+func (m Affine) Then(n Affine) Affine {
+	return Affine{
+		A: n.A*m.A + n.B*m.D,
+		B: n.A*m.B + n.B*m.E,
+		C: n.A*m.C + n.B*m.F + n.C,
+		D: n.D*m.A + n.E*m.D,
+		E: n.D*m.B + n.E*m.E,
+		F: n.D*m.C + n.E*m.F + n.F,
+	}
+}
+
+// Invert returns the transform undoing m. ok is false when m flattens the
+// plane onto a line or a point, which can't be undone.
+func (m Affine) Invert() (inv Affine, ok bool) {
+	det := m.A*m.E - m.B*m.D
+	if ShapeTolerance.Equal(det, 0) {
+		return Affine{}, false
+	}
+	return Affine{
+		A: m.E / det,
+		B: -m.B / det,
+		C: (m.B*m.F - m.E*m.C) / det,
+		D: -m.D / det,
+		E: m.A / det,
+		F: (m.D*m.C - m.A*m.F) / det,
+	}, true
+}
+
+// Apply maps a point
+func (m Affine) Apply(p Point) Point {
+	return Point{m.A*p.X + m.B*p.Y + m.C, m.D*p.X + m.E*p.Y + m.F}
+}
+
+// vector maps a direction, ignoring the translation
+func (m Affine) vector(v Point) Point {
+	return Point{m.A*v.X + m.B*v.Y, m.D*v.X + m.E*v.Y}
+}
+
+// similarity returns the scale factor of m if it keeps shapes similar
+// (rotation, reflection, uniform scale and translation)
+func (m Affine) similarity() (float64, bool) {
+	u, v := m.vector(Point{1, 0}), m.vector(Point{0, 1})
+	lu, lv := math.Hypot(u.X, u.Y), math.Hypot(v.X, v.Y)
+	return lu, ShapeTolerance.Equal(lu, lv) && perpendicular(u, v)
+}
+
+// perpendicular compares the cosine of the angle between u and v with 0
+func perpendicular(u, v Point) bool {
+	l := math.Hypot(u.X, u.Y) * math.Hypot(v.X, v.Y)
+	return l == 0 || ShapeTolerance.Equal((u.X*v.X+u.Y*v.Y)/l, 0)
+}
+
+// Translate moves s by dx, dy
+func Translate(s Shape, dx, dy float64) Shape {
+	return s.Transform(Translation(dx, dy))
+}
+
+// Rotate turns s by theta radians around its centroid
+func Rotate(s Shape, theta float64) Shape {
+	return s.Transform(Rotation(theta).About(s.Centroid()))
+}
+
+// Scale stretches s around its centroid
+func Scale(s Shape, sx, sy float64) Shape {
+	return s.Transform(Scaling(sx, sy).About(s.Centroid()))
+}
+
+// Box is an axis-aligned bounding box
+type Box struct {
+	Min, Max Point
+}
+
+func boxOf(points ...Point) Box {
+	b := Box{Point{math.Inf(1), math.Inf(1)}, Point{math.Inf(-1), math.Inf(-1)}}
+	for _, p := range points {
+		b.Min.X, b.Min.Y = math.Min(b.Min.X, p.X), math.Min(b.Min.Y, p.Y)
+		b.Max.X, b.Max.Y = math.Max(b.Max.X, p.X), math.Max(b.Max.Y, p.Y)
+	}
+	return b
+}
+
+// Union is the box containing both
+func (b Box) Union(o Box) Box {
+	switch {
+	case b.Empty():
+		return o
+	case o.Empty():
+		return b
+	}
+	return boxOf(b.Min, b.Max, o.Min, o.Max)
+}
+
+// Empty tells whether the box contains nothing, like the box of no points
+func (b Box) Empty() bool { return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y }
+
+// Width of the box
+func (b Box) Width() float64 { return b.Max.X - b.Min.X }
+
+// Height of the box
+func (b Box) Height() float64 { return b.Max.Y - b.Min.Y }
+
+// OBB is an oriented bounding box, a rectangle turned by Angle radians
+type OBB struct {
+	Center       Point
+	HalfW, HalfH float64
+	Angle        float64
+}
+
+// Corners lists the corners counterclockwise
+func (o OBB) Corners() []Point {
+	m := Rotation(o.Angle).Then(Translation(o.Center.X, o.Center.Y))
+	return []Point{
+		m.Apply(Point{-o.HalfW, -o.HalfH}),
+		m.Apply(Point{o.HalfW, -o.HalfH}),
+		m.Apply(Point{o.HalfW, o.HalfH}),
+		m.Apply(Point{-o.HalfW, o.HalfH}),
+	}
+}
+
+// Area of the box
+func (o OBB) Area() float64 { return 4 * o.HalfW * o.HalfH }
+
+// convexHull returns the hull of points counterclockwise (monotone chain)
+func convexHull(points []Point) []Point {
+	ps := append([]Point(nil), points...)
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].X < ps[j].X || ps[i].X == ps[j].X && ps[i].Y < ps[j].Y
+	})
+	if len(ps) < 3 {
+		return ps
+	}
+	cross := func(o, a, b Point) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	hull := make([]Point, 0, 2*len(ps))
+	for _, p := range ps {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(ps) - 2; i >= 0; i-- {
+		p := ps[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// minimumBox finds the smallest box around points. One of its sides lies
+// on an edge of the convex hull, so only those directions are tried.
+func minimumBox(points []Point) OBB {
+	hull := convexHull(points)
+	if len(hull) == 0 {
+		return OBB{}
+	}
+	best := OBB{Center: hull[0]}
+	bestArea := math.Inf(1)
+	for i, a := range hull {
+		b := hull[(i+1)%len(hull)]
+		if a == b {
+			continue
+		}
+		angle := math.Atan2(b.Y-a.Y, b.X-a.X)
+		box := boxOf(Rotation(-angle).applyAll(hull)...)
+		// within the tolerance the first direction wins, keeping the result
+		// stable; any area beats the initial infinity
+		area := box.Width() * box.Height()
+		if math.IsInf(bestArea, 1) || area < bestArea && !ShapeTolerance.Equal(area, bestArea) {
+			bestArea = area
+			center := Point{(box.Min.X + box.Max.X) / 2, (box.Min.Y + box.Max.Y) / 2}
+			best = OBB{Rotation(angle).Apply(center), box.Width() / 2, box.Height() / 2, angle}
+		}
+	}
+	return best
+}
+
+// centroidOf is the average of points
+func centroidOf(points []Point) Point {
+	var c Point
+	for _, p := range points {
+		c.X += p.X
+		c.Y += p.Y
+	}
+	n := float64(len(points))
+	return Point{c.X / n, c.Y / n}
+}
+
+// ellipseThrough returns the image of the unit circle by the linear map
+// [[a b] [c d]] centered at center, using the singular value decomposition
+// of the map
+func ellipseThrough(center Point, a, b, c, d float64) Ellipse {
+	e, f := (a+d)/2, (a-d)/2
+	g, h := (c+b)/2, (c-b)/2
+	q, r := math.Hypot(e, h), math.Hypot(f, g)
+	a1, a2 := math.Atan2(g, f), math.Atan2(h, e)
+	return Ellipse{A: q + r, B: math.Abs(q - r), Center: center, Angle: (a2 + a1) / 2}
+}
+
+// rect
+
+func (r rect) corners() []Point {
+	return OBB{r.center, r.width / 2, r.height / 2, r.angle}.Corners()
+}
+
+// Centroid of the rect
+func (r rect) Centroid() Point { return r.center }
+
+// Transform keeps a rect as long as its corners stay right angles
+func (r rect) Transform(m Affine) Shape {
+	sin, cos := math.Sincos(r.angle)
+	u := m.vector(Point{cos * r.width, sin * r.width})
+	v := m.vector(Point{-sin * r.height, cos * r.height})
+	if !perpendicular(u, v) {
+		return Polygon{Vertices: m.applyAll(r.corners())}
+	}
+	return rect{
+		width:  math.Hypot(u.X, u.Y),
+		height: math.Hypot(v.X, v.Y),
+		center: m.Apply(r.center),
+		angle:  math.Atan2(u.Y, u.X),
+	}
+}
+
+// BoundingBox of the rect
+func (r rect) BoundingBox() Box { return boxOf(r.corners()...) }
+
+// OrientedBox of a rect is itself
+func (r rect) OrientedBox() OBB { return OBB{r.center, r.width / 2, r.height / 2, r.angle} }
+
+// circle
+
+// Centroid of the circle
+func (c circle) Centroid() Point { return c.center }
+
+// Transform keeps a circle unless m stretches it into an ellipse
+func (c circle) Transform(m Affine) Shape {
+	return Ellipse{A: c.radius, B: c.radius, Center: c.center}.Transform(m)
+}
+
+// BoundingBox of the circle
+func (c circle) BoundingBox() Box {
+	return Box{Point{c.center.X - c.radius, c.center.Y - c.radius}, Point{c.center.X + c.radius, c.center.Y + c.radius}}
+}
+
+// OrientedBox of the circle, any direction would do
+func (c circle) OrientedBox() OBB { return OBB{c.center, c.radius, c.radius, 0} }
+
+// Ellipse
+
+// Centroid of the ellipse
+func (e Ellipse) Centroid() Point { return e.Center }
+
+// Transform keeps an ellipse, or a circle if the axes become equal
+func (e Ellipse) Transform(m Affine) Shape {
+	// the ellipse is the unit circle through R(angle) * diag(A, B)
+	sin, cos := math.Sincos(e.Angle)
+	l := Affine{A: cos * e.A, B: -sin * e.B, D: sin * e.A, E: cos * e.B}.Then(m)
+	out := ellipseThrough(m.Apply(e.Center), l.A, l.B, l.D, l.E)
+	if ShapeTolerance.Equal(out.A, out.B) {
+		return circle{radius: out.A, center: out.Center}
+	}
+	return out
+}
+
+// BoundingBox of the ellipse
+func (e Ellipse) BoundingBox() Box {
+	sin, cos := math.Sincos(e.Angle)
+	w := math.Hypot(e.A*cos, e.B*sin)
+	h := math.Hypot(e.A*sin, e.B*cos)
+	return Box{Point{e.Center.X - w, e.Center.Y - h}, Point{e.Center.X + w, e.Center.Y + h}}
+}
+
+// OrientedBox of the ellipse follows its axes
+func (e Ellipse) OrientedBox() OBB { return OBB{e.Center, e.A, e.B, e.Angle} }
+
+// RegularPolygon
+
+// Vertices lists the corners, the first one at Angle from the center. A
+// polygon with fewer than 3 sides has none.
+func (r RegularPolygon) Vertices() []Point {
+	if r.Sides < 3 {
+		return nil
+	}
+	vs := make([]Point, r.Sides)
+	for i := range vs {
+		sin, cos := math.Sincos(r.Angle + 2*math.Pi*float64(i)/float64(r.Sides))
+		vs[i] = Point{r.Center.X + r.Radius*cos, r.Center.Y + r.Radius*sin}
+	}
+	return vs
+}
+
+// Centroid of the polygon
+func (r RegularPolygon) Centroid() Point { return r.Center }
+
+// Transform keeps a regular polygon under similarities
+func (r RegularPolygon) Transform(m Affine) Shape {
+	if s, ok := m.similarity(); ok {
+		center := m.Apply(r.Center)
+		if r.Sides < 3 {
+			return RegularPolygon{r.Sides, r.Radius * s, center, r.Angle}
+		}
+		first := m.Apply(r.Vertices()[0])
+		return RegularPolygon{r.Sides, r.Radius * s, center, math.Atan2(first.Y-center.Y, first.X-center.X)}
+	}
+	return Polygon{Vertices: m.applyAll(r.Vertices())}
+}
+
+// BoundingBox of the polygon
+func (r RegularPolygon) BoundingBox() Box { return boxOf(r.Vertices()...) }
+
+// OrientedBox of the polygon
+func (r RegularPolygon) OrientedBox() OBB { return minimumBox(r.Vertices()) }
+
+// Triangle
+
+// Centroid of the triangle
+func (t Triangle) Centroid() Point { return centroidOf([]Point{t.A, t.B, t.C}) }
+
+// Transform maps the vertices
+func (t Triangle) Transform(m Affine) Shape {
+	return Triangle{m.Apply(t.A), m.Apply(t.B), m.Apply(t.C)}
+}
+
+// BoundingBox of the triangle
+func (t Triangle) BoundingBox() Box { return boxOf(t.A, t.B, t.C) }
+
+// OrientedBox of the triangle
+func (t Triangle) OrientedBox() OBB { return minimumBox([]Point{t.A, t.B, t.C}) }
+
+// Polygon
+
+// Centroid of the polygon, or the average of its vertices if it has no
+// area
+func (p Polygon) Centroid() Point {
+	var cx, cy, sum float64
+	for i, a := range p.Vertices {
+		b := p.Vertices[(i+1)%len(p.Vertices)]
+		cross := a.X*b.Y - b.X*a.Y
+		sum += cross
+		cx += (a.X + b.X) * cross
+		cy += (a.Y + b.Y) * cross
+	}
+	if ShapeTolerance.Equal(sum, 0) {
+		return centroidOf(p.Vertices)
+	}
+	return Point{cx / (3 * sum), cy / (3 * sum)}
+}
+
+// Transform maps the vertices
+func (p Polygon) Transform(m Affine) Shape { return Polygon{m.applyAll(p.Vertices)} }
+
+// BoundingBox of the polygon
+func (p Polygon) BoundingBox() Box { return boxOf(p.Vertices...) }
+
+// OrientedBox of the polygon
+func (p Polygon) OrientedBox() OBB { return minimumBox(p.Vertices) }
+
+// LineSegment
+
+// Centroid is the middle of the segment
+func (l LineSegment) Centroid() Point { return centroidOf([]Point{l.A, l.B}) }
+
+// Transform maps the ends
+func (l LineSegment) Transform(m Affine) Shape { return LineSegment{m.Apply(l.A), m.Apply(l.B)} }
+
+// BoundingBox of the segment
+func (l LineSegment) BoundingBox() Box { return boxOf(l.A, l.B) }
+
+// OrientedBox of the segment has no height
+func (l LineSegment) OrientedBox() OBB { return minimumBox([]Point{l.A, l.B}) }
+
+// Composite
+
+// Centroid is the average of the centroids of the children weighted by
+// their area
+func (c Composite) Centroid() Point {
+	var x, y, total float64
+	var centroids []Point
+	for _, s := range c.Shapes {
+		p, a := s.Centroid(), s.area()
+		x += p.X * a
+		y += p.Y * a
+		total += a
+		centroids = append(centroids, p)
+	}
+	if ShapeTolerance.Equal(total, 0) {
+		return centroidOf(centroids)
+	}
+	return Point{x / total, y / total}
+}
+
+// Transform moves every child
+func (c Composite) Transform(m Affine) Shape {
+	out := Composite{Shapes: make([]Shape, len(c.Shapes))}
+	for i, s := range c.Shapes {
+		out.Shapes[i] = s.Transform(m)
+	}
+	return out
+}
+
+// BoundingBox contains the boxes of the children
+func (c Composite) BoundingBox() Box {
+	b := boxOf()
+	for _, s := range c.Shapes {
+		b = b.Union(s.BoundingBox())
+	}
+	return b
+}
+
+// OrientedBox contains the oriented boxes of the children
+func (c Composite) OrientedBox() OBB {
+	var points []Point
+	for _, s := range c.Shapes {
+		points = append(points, s.OrientedBox().Corners()...)
+	}
+	return minimumBox(points)
+}
+
+func (m Affine) applyAll(points []Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[i] = m.Apply(p)
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package examples
+
+import (
+	"math"
+	"testing"
+)
+
+func nearPoint(a, b Point) bool {
+	return ShapeTolerance.Equal(a.X, b.X) && ShapeTolerance.Equal(a.Y, b.Y)
+}
+
+func nearAffine(m, n Affine) bool {
+	for _, pair := range [][2]float64{{m.A, n.A}, {m.B, n.B}, {m.C, n.C}, {m.D, n.D}, {m.E, n.E}, {m.F, n.F}} {
+		if !ShapeTolerance.Equal(pair[0], pair[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAffineCompose(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Affine
+		in, out Point
+	}{
+		{"identity", Identity, Point{3, -2}, Point{3, -2}},
+		{"translation", Translation(1, 2), Point{3, -2}, Point{4, 0}},
+		{"rotation", Rotation(math.Pi / 2), Point{1, 0}, Point{0, 1}},
+		{"scaling", Scaling(2, 3), Point{1, 1}, Point{2, 3}},
+		{"translate then rotate", Translation(1, 0).Then(Rotation(math.Pi / 2)), Point{0, 0}, Point{0, 1}},
+		{"rotate then translate", Rotation(math.Pi / 2).Then(Translation(1, 0)), Point{0, 0}, Point{1, 0}},
+		{"scale then translate", Scaling(2, 2).Then(Translation(1, 1)), Point{1, 0}, Point{3, 1}},
+		{"rotation about a point", Rotation(math.Pi).About(Point{1, 1}), Point{0, 0}, Point{2, 2}},
+		{"scaling about a point", Scaling(2, 2).About(Point{1, 1}), Point{1, 1}, Point{1, 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Apply(tt.in); !nearPoint(got, tt.out) {
+			t.Errorf("%s: %v -> %v, want %v", tt.name, tt.in, got, tt.out)
+		}
+	}
+
+	// Then is associative and Identity is neutral
+	a, b, c := Rotation(0.3), Translation(2, -1), Scaling(1.5, 0.5)
+	if !nearAffine(a.Then(b).Then(c), a.Then(b.Then(c))) {
+		t.Error("Then is not associative")
+	}
+	if !nearAffine(Identity.Then(a), a) || !nearAffine(a.Then(Identity), a) {
+		t.Error("Identity changes the transform")
+	}
+}
+
+func TestAffineInvert(t *testing.T) {
+	invertible := []Affine{
+		Identity,
+		Translation(3, -4),
+		Rotation(1.2),
+		Scaling(2, -0.5),
+		{A: 1, B: 0.5, E: 1},
+		Rotation(0.7).About(Point{2, 3}).Then(Scaling(3, 2)).Then(Translation(-1, 5)),
+	}
+	for _, m := range invertible {
+		inv, ok := m.Invert()
+		if !ok {
+			t.Errorf("%+v: not invertible", m)
+			continue
+		}
+		if !nearAffine(m.Then(inv), Identity) || !nearAffine(inv.Then(m), Identity) {
+			t.Errorf("%+v: inverse %+v doesn't undo it", m, inv)
+		}
+		p := Point{1.5, -2.5}
+		if got := inv.Apply(m.Apply(p)); !nearPoint(got, p) {
+			t.Errorf("%+v: %v comes back as %v", m, p, got)
+		}
+	}
+
+	// toUnit is the inverse of placing the unit circle
+	e := Ellipse{A: 3, B: 1, Center: Point{2, -1}, Angle: 0.4}
+	place := Scaling(e.A, e.B).Then(Rotation(e.Angle)).Then(Translation(e.Center.X, e.Center.Y))
+	if inv, _ := place.Invert(); !nearAffine(inv, e.toUnit()) {
+		t.Errorf("toUnit %+v, inverse of the placement %+v", e.toUnit(), inv)
+	}
+
+	for _, m := range []Affine{{}, Scaling(0, 1), {A: 1, B: 2, D: 2, E: 4}} {
+		if _, ok := m.Invert(); ok {
+			t.Errorf("%+v: inverted a flattening transform", m)
+		}
+	}
+}
+
+func TestOrientedBox(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      Shape
+		area   float64
+		halves [2]float64 // half sizes, the larger first
+	}{
+		{"axis aligned rect", rect{width: 4, height: 2, center: Point{1, 1}}, 8, [2]float64{2, 1}},
+		{"rotated rect", rect{width: 4, height: 2, angle: 0.5}, 8, [2]float64{2, 1}},
+		{"rect as polygon", Polygon{rect{width: 4, height: 2, angle: 0.5}.corners()}, 8, [2]float64{2, 1}},
+		{"square regular polygon", RegularPolygon{Sides: 4, Radius: math.Sqrt2, Angle: math.Pi / 4}, 4, [2]float64{1, 1}},
+		{"right triangle", Triangle{Point{0, 0}, Point{4, 0}, Point{0, 3}}, 12, [2]float64{2, 1.5}},
+		{"flat triangle", Triangle{Point{0, 0}, Point{4, 0}, Point{2, 1}}, 4, [2]float64{2, 0.5}},
+		{"tilted flat triangle", Rotate(Triangle{Point{0, 0}, Point{4, 0}, Point{2, 1}}, 1), 4, [2]float64{2, 0.5}},
+		{"segment", LineSegment{Point{0, 0}, Point{3, 4}}, 0, [2]float64{2.5, 0}},
+		{"U polygon", uShape, 9, [2]float64{1.5, 1.5}},
+		{"composite", Composite{[]Shape{rect{width: 2, height: 2}, rect{width: 2, height: 2, center: Point{4, 0}}}}, 12, [2]float64{3, 1}},
+	}
+	for _, tt := range tests {
+		box := tt.s.OrientedBox()
+		halves := [2]float64{math.Max(box.HalfW, box.HalfH), math.Min(box.HalfW, box.HalfH)}
+		if !ShapeTolerance.Equal(box.Area(), tt.area) ||
+			!ShapeTolerance.Equal(halves[0], tt.halves[0]) || !ShapeTolerance.Equal(halves[1], tt.halves[1]) {
+			t.Errorf("%s: box %+v, want half sizes %v", tt.name, box, tt.halves)
+			continue
+		}
+		// the shape fits in the box
+		inside := Translation(-box.Center.X, -box.Center.Y).Then(Rotation(-box.Angle))
+		for _, p := range vertices(tt.s) {
+			q := inside.Apply(p)
+			if !ShapeTolerance.LessOrEqual(math.Abs(q.X), box.HalfW) || !ShapeTolerance.LessOrEqual(math.Abs(q.Y), box.HalfH) {
+				t.Errorf("%s: %v outside the box %+v", tt.name, p, box)
+			}
+		}
+	}
+}
+
+// vertices lists the corners of a shape bounded by straight edges
+func vertices(s Shape) []Point {
+	if c, ok := s.(Composite); ok {
+		var list []Point
+		for _, child := range c.Shapes {
+			list = append(list, vertices(child)...)
+		}
+		return list
+	}
+	list, _ := outline(s)
+	return list
+}
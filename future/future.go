@@ -0,0 +1,201 @@
+// Package future wraps the result of an asynchronous call, replacing the
+// "make a channel, start a goroutine, select with time.After" pattern.
+package future
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/group"
+)
+
+// ErrNoFutures is returned by the combinators when given no future
+var ErrNoFutures = errors.New("future: nothing to wait for")
+
+// Future is a value that will be available later
+type Future[T any] struct {
+	ctx  context.Context
+	done chan struct{}
+	val  T
+	err  error
+}
+
+func newFuture[T any](ctx context.Context) *Future[T] {
+	return &Future[T]{ctx: ctx, done: make(chan struct{})}
+}
+
+func (f *Future[T]) resolve(val T, err error) {
+	f.val = val
+	f.err = err
+	close(f.done)
+}
+
+// Async runs fn in a new goroutine. A panic in fn becomes a
+// *group.PanicError.
+func Async[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	f := newFuture[T](ctx)
+	go func() {
+		var val T
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = &group.PanicError{Value: r, Stack: debug.Stack()}
+			}
+			f.resolve(val, err)
+		}()
+		val, err = fn(ctx)
+	}()
+	return f
+}
+
+// Resolved returns a future already holding val
+func Resolved[T any](val T) *Future[T] {
+	f := newFuture[T](context.Background())
+	f.resolve(val, nil)
+	return f
+}
+
+// Rejected returns a future already holding err
+func Rejected[T any](err error) *Future[T] {
+	f := newFuture[T](context.Background())
+	var zero T
+	f.resolve(zero, err)
+	return f
+}
+
+// Done is closed once the result is available
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result or for ctx to be done. Giving up does not stop
+// the call: the result is still available to later Awaits.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// AwaitTimeout waits at most d. On timeout the error is
+// context.DeadlineExceeded.
+func (f *Future[T]) AwaitTimeout(d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return f.Await(ctx)
+}
+
+// Then runs fn with the result of f once it succeeds. An error in f is
+// passed along without calling fn.
+func Then[T, U any](f *Future[T], fn func(ctx context.Context, val T) (U, error)) *Future[U] {
+	return Async(f.ctx, func(ctx context.Context) (U, error) {
+		val, err := f.Await(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(ctx, val)
+	})
+}
+
+// Map converts the result of f with fn, which cannot fail
+func Map[T, U any](f *Future[T], fn func(T) U) *Future[U] {
+	return Then(f, func(_ context.Context, val T) (U, error) {
+		return fn(val), nil
+	})
+}
+
+// All waits for every future and returns their results in order. It fails
+// as soon as one of them fails.
+func All[T any](ctx context.Context, fs ...*Future[T]) *Future[[]T] {
+	return Async(ctx, func(ctx context.Context) ([]T, error) {
+		results := make([]T, len(fs))
+		ready, stop := watch(fs)
+		defer stop()
+		for range fs {
+			i, err := next(ctx, ready)
+			if err != nil {
+				return nil, err
+			}
+			if fs[i].err != nil {
+				return nil, fs[i].err
+			}
+			results[i] = fs[i].val
+		}
+		return results, nil
+	})
+}
+
+// Any returns the first successful result. If all fail, their errors are
+// joined.
+func Any[T any](ctx context.Context, fs ...*Future[T]) *Future[T] {
+	return Async(ctx, func(ctx context.Context) (T, error) {
+		var zero T
+		if len(fs) == 0 {
+			return zero, ErrNoFutures
+		}
+		ready, stop := watch(fs)
+		defer stop()
+		errs := make([]error, len(fs))
+		for range fs {
+			i, err := next(ctx, ready)
+			if err != nil {
+				return zero, err
+			}
+			if fs[i].err == nil {
+				return fs[i].val, nil
+			}
+			errs[i] = fs[i].err
+		}
+		return zero, errors.Join(errs...)
+	})
+}
+
+// Race returns the result of the first future to finish, success or not
+func Race[T any](ctx context.Context, fs ...*Future[T]) *Future[T] {
+	return Async(ctx, func(ctx context.Context) (T, error) {
+		var zero T
+		if len(fs) == 0 {
+			return zero, ErrNoFutures
+		}
+		ready, stop := watch(fs)
+		defer stop()
+		i, err := next(ctx, ready)
+		if err != nil {
+			return zero, err
+		}
+		return fs[i].val, fs[i].err
+	})
+}
+
+// watch starts one watcher per future, which sends the index of the future
+// on ready once it is done. stop ends the watchers still waiting.
+func watch[T any](fs []*Future[T]) (ready <-chan int, stop func()) {
+	ch := make(chan int, len(fs))
+	quit := make(chan struct{})
+	for i, f := range fs {
+		go func(i int, f *Future[T]) {
+			select {
+			case <-f.done:
+				ch <- i
+			case <-quit:
+			}
+		}(i, f)
+	}
+	return ch, func() { close(quit) }
+}
+
+// next waits for the index of the next future to finish
+func next(ctx context.Context, ready <-chan int) (int, error) {
+	select {
+	case i := <-ready:
+		return i, nil
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+}
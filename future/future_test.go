@@ -0,0 +1,133 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/group"
+)
+
+var (
+	errA = errors.New("a failed")
+	errB = errors.New("b failed")
+)
+
+// pending returns a future resolved by calling the returned function
+func pending[T any]() (*Future[T], func(T, error)) {
+	f := newFuture[T](context.Background())
+	return f, f.resolve
+}
+
+func TestThenMap(t *testing.T) {
+	ctx := context.Background()
+	f := Map(Then(Resolved(21), func(_ context.Context, v int) (int, error) {
+		return v * 2, nil
+	}), strconv.Itoa)
+	if v, err := f.Await(ctx); v != "42" || err != nil {
+		t.Errorf("got %q, %v, want 42, nil", v, err)
+	}
+
+	called := false
+	g := Map(Then(Rejected[int](errA), func(_ context.Context, v int) (int, error) {
+		called = true
+		return v, nil
+	}), strconv.Itoa)
+	if _, err := g.Await(ctx); err != errA {
+		t.Errorf("got %v, want %v", err, errA)
+	}
+	if called {
+		t.Error("Then called fn after an error")
+	}
+
+	h := Then(Resolved(1), func(context.Context, int) (int, error) { return 0, errB })
+	if _, err := Map(h, strconv.Itoa).Await(ctx); err != errB {
+		t.Errorf("got %v, want %v", err, errB)
+	}
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+	a, resolveA := pending[int]()
+	b, resolveB := pending[int]()
+	all := All(ctx, a, b, Resolved(3))
+	resolveB(2, nil)
+	resolveA(1, nil)
+	v, err := all.Await(ctx)
+	if err != nil || len(v) != 3 || v[0] != 1 || v[1] != 2 || v[2] != 3 {
+		t.Errorf("got %v, %v, want [1 2 3], nil", v, err)
+	}
+
+	// fails without waiting for the future never resolved
+	never, _ := pending[int]()
+	if _, err := All(ctx, never, Rejected[int](errA)).AwaitTimeout(time.Second); err != errA {
+		t.Errorf("got %v, want %v", err, errA)
+	}
+
+	if v, err := All[int](ctx).Await(ctx); err != nil || len(v) != 0 {
+		t.Errorf("no futures: got %v, %v", v, err)
+	}
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+	never, _ := pending[int]()
+	if v, err := Any(ctx, Rejected[int](errA), never, Resolved(2)).AwaitTimeout(time.Second); v != 2 || err != nil {
+		t.Errorf("got %v, %v, want 2, nil", v, err)
+	}
+
+	_, err := Any(ctx, Rejected[int](errA), Rejected[int](errB)).Await(ctx)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("got %v, want both errors joined", err)
+	}
+
+	if _, err := Any[int](ctx).Await(ctx); err != ErrNoFutures {
+		t.Errorf("no futures: got %v, want %v", err, ErrNoFutures)
+	}
+}
+
+func TestRace(t *testing.T) {
+	ctx := context.Background()
+	never, _ := pending[int]()
+	if _, err := Race(ctx, never, Rejected[int](errA)).AwaitTimeout(time.Second); err != errA {
+		t.Errorf("got %v, want %v", err, errA)
+	}
+	if v, err := Race(ctx, never, Resolved(1)).AwaitTimeout(time.Second); v != 1 || err != nil {
+		t.Errorf("got %v, %v, want 1, nil", v, err)
+	}
+	if _, err := Race[int](ctx).Await(ctx); err != ErrNoFutures {
+		t.Errorf("no futures: got %v, want %v", err, ErrNoFutures)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Race(cctx, never).Await(ctx); err != context.Canceled {
+		t.Errorf("canceled: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAsyncPanic(t *testing.T) {
+	f := Async(context.Background(), func(context.Context) (int, error) {
+		panic(errA)
+	})
+	_, err := f.Await(context.Background())
+	var perr *group.PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %T, want *group.PanicError", err)
+	}
+	if perr.Value != errA || !errors.Is(err, errA) {
+		t.Errorf("panic value %v, want %v", perr.Value, errA)
+	}
+	if len(perr.Stack) == 0 {
+		t.Error("no stack captured")
+	}
+}
+
+func TestAwaitTimeout(t *testing.T) {
+	never, _ := pending[int]()
+	if _, err := never.AwaitTimeout(time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,145 @@
+// Package group runs related goroutines and waits for all of them, in the
+// spirit of golang.org/x/sync/errgroup, but keeping every error.
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is returned in place of a goroutine that panicked
+type PanicError struct {
+	Value any
+	Stack []byte // of the goroutine that panicked, printed with %+v
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Format prints the stack after the message with %+v
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%s\n\n%s", e.Error(), e.Stack)
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// Unwrap gives access to the panic value when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Group is a collection of goroutines working on the same task. The zero
+// value is ready to use, has no concurrency limit and never cancels.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithContext returns a group whose context is canceled as soon as a
+// goroutine fails, or when Wait returns
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// SetLimit caps the number of goroutines running at once. A negative value
+// removes the limit. It must not be called while goroutines are running.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs fn in a new goroutine, blocking while the limit is reached
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(fn)
+}
+
+// TryGo runs fn only if the limit allows it right away
+func (g *Group) TryGo(fn func(ctx context.Context) error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(fn)
+	return true
+}
+
+func (g *Group) start(fn func(ctx context.Context) error) {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		if err := call(ctx, fn); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+// call converts a panic of fn into a PanicError
+func call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	g.errs = append(g.errs, err)
+	g.mu.Unlock()
+	if g.cancel != nil {
+		g.cancel(err)
+	}
+}
+
+// Wait blocks until every goroutine returned and joins all their errors
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
@@ -0,0 +1,127 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var errFailed = errors.New("failed")
+
+func TestLimit(t *testing.T) {
+	var g Group
+	g.SetLimit(2)
+	var running, peak atomic.Int32
+	var once sync.Once
+	full := make(chan struct{})
+	for i := 0; i < 6; i++ {
+		g.Go(func(context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			// hold the first goroutines until the limit is reached
+			if n == 2 {
+				once.Do(func() { close(full) })
+			}
+			<-full
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("%d goroutines at once, want 2", p)
+	}
+}
+
+func TestTryGo(t *testing.T) {
+	var g Group
+	g.SetLimit(1)
+	block := make(chan struct{})
+	if !g.TryGo(func(context.Context) error { <-block; return nil }) {
+		t.Fatal("TryGo failed with a free slot")
+	}
+	if g.TryGo(func(context.Context) error { return nil }) {
+		t.Error("TryGo ran past the limit")
+	}
+	close(block)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !g.TryGo(func(context.Context) error { return nil }) {
+		t.Error("TryGo failed after the slot was freed")
+	}
+	g.Wait()
+}
+
+func TestFirstErrorCancels(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go(func(context.Context) error { return errFailed })
+	err := g.Wait()
+	if !errors.Is(err, errFailed) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want both errors", err)
+	}
+	if cause := context.Cause(ctx); cause != errFailed {
+		t.Errorf("cause %v, want %v", cause, errFailed)
+	}
+}
+
+func TestWaitCancels(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	g.Go(func(context.Context) error { return nil })
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Error("context not canceled by Wait")
+	}
+	if cause := context.Cause(ctx); cause != context.Canceled {
+		t.Errorf("cause %v, want %v", cause, context.Canceled)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var g Group
+	g.Go(func(context.Context) error { panic(errFailed) })
+	g.Go(func(context.Context) error { panic("boom") })
+	err := g.Wait()
+	if !errors.Is(err, errFailed) {
+		t.Errorf("errors.Is does not see the panic value: %v", err)
+	}
+	var perr *PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %T, want a *PanicError", err)
+	}
+	if len(perr.Stack) == 0 {
+		t.Error("no stack captured")
+	}
+
+	p := &PanicError{Value: "boom", Stack: []byte("goroutine 1 [running]:")}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%v", "panic: boom"},
+		{"%s", "panic: boom"},
+		{"%q", `"panic: boom"`},
+		{"%+v", "panic: boom\n\ngoroutine 1 [running]:"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, p); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	if got := p.Error(); strings.Contains(got, "goroutine") {
+		t.Errorf("Error() includes the stack: %q", got)
+	}
+}
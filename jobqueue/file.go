@@ -0,0 +1,71 @@
+package jobqueue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// snapshot is the content of the queue file
+type snapshot[T any] struct {
+	Seq  uint64   `json:"seq"`
+	Jobs []Job[T] `json:"jobs"`
+}
+
+// save writes s to a temporary file and renames it over the queue file, so
+// a crash never leaves a half written file
+func (q *Queue[T]) save(s *state[T]) error {
+	snap := snapshot[T]{Seq: s.seq}
+	for _, job := range s.sorted() {
+		snap.Jobs = append(snap.Jobs, *job)
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("jobqueue: saving: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(q.file), filepath.Base(q.file)+".*")
+	if err != nil {
+		return fmt.Errorf("jobqueue: saving: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("jobqueue: saving: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("jobqueue: saving: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("jobqueue: saving: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.file); err != nil {
+		return fmt.Errorf("jobqueue: saving: %w", err)
+	}
+	return nil
+}
+
+// load reads the queue file if it exists. Jobs that were in flight keep
+// their deadline, so the deliveries lost with the previous run are made
+// again once they expire.
+func (q *Queue[T]) load() error {
+	data, err := os.ReadFile(q.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("jobqueue: loading: %w", err)
+	}
+	var snap snapshot[T]
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("jobqueue: loading %s: %w", q.file, err)
+	}
+	q.st.seq = snap.Seq
+	for i := range snap.Jobs {
+		job := snap.Jobs[i]
+		q.st.jobs[job.ID] = &job
+	}
+	return nil
+}
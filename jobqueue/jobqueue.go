@@ -0,0 +1,455 @@
+// Package jobqueue is an in-process work queue with acknowledgements.
+//
+// A received job stays invisible to other workers for the visibility
+// timeout. If it is not acknowledged in time, because the worker crashed or
+// is stuck, it is delivered again. Jobs failing too many times are moved to
+// a dead letter list. Optionally the queue is saved to a file after every
+// change, so jobs survive a restart of the program.
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+// Job states
+const (
+	Ready    = "ready"
+	InFlight = "in-flight"
+	Dead     = "dead"
+)
+
+var (
+	// ErrNotInFlight is returned when acknowledging a job whose delivery
+	// expired or was already acknowledged
+	ErrNotInFlight = errors.New("jobqueue: job is not in flight for this delivery")
+	// ErrClosed is returned by a closed queue
+	ErrClosed = errors.New("jobqueue: closed")
+)
+
+// Job is a unit of work and its delivery state
+type Job[T any] struct {
+	ID       string `json:"id"`
+	Seq      uint64 `json:"seq"`
+	Payload  T      `json:"payload"`
+	Attempts int    `json:"attempts"`
+	State    string `json:"state"`
+	// Until is when a ready job becomes visible, or when the delivery of an
+	// in-flight job expires
+	Until     time.Time `json:"until"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Stats counts jobs by state
+type Stats struct {
+	Ready    int // visible now
+	Delayed  int // ready in the future
+	InFlight int
+	Dead     int
+}
+
+// Queue holds jobs with payloads of type T
+type Queue[T any] struct {
+	visibility  time.Duration
+	maxAttempts int
+	clock       schedule.Clock
+	file        string
+
+	mu      sync.Mutex
+	st      *state[T]
+	changed chan struct{}
+	closed  bool
+}
+
+// state is the content of a queue. Changes are made to a copy, which
+// replaces the state only once it is saved.
+type state[T any] struct {
+	seq  uint64
+	jobs map[string]*Job[T]
+}
+
+func (s *state[T]) clone() *state[T] {
+	c := &state[T]{seq: s.seq, jobs: make(map[string]*Job[T], len(s.jobs))}
+	for id, job := range s.jobs {
+		j := *job
+		c.jobs[id] = &j
+	}
+	return c
+}
+
+// sorted returns the jobs in enqueue order
+func (s *state[T]) sorted() []*Job[T] {
+	list := make([]*Job[T], 0, len(s.jobs))
+	for _, job := range s.jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Seq < list[j].Seq
+	})
+	return list
+}
+
+// Option configures a queue
+type Option func(*options)
+
+type options struct {
+	visibility  time.Duration
+	maxAttempts int
+	clock       schedule.Clock
+	file        string
+}
+
+// Visibility sets how long a delivery lasts before the job is delivered
+// again (30s by default)
+func Visibility(d time.Duration) Option {
+	return func(o *options) {
+		o.visibility = d
+	}
+}
+
+// MaxAttempts sets how many deliveries a job gets before it is dead (5 by
+// default, 0 means no limit)
+func MaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithClock replaces the real clock
+func WithClock(c schedule.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// File keeps the queue in the given file, loading it if it exists
+func File(path string) Option {
+	return func(o *options) {
+		o.file = path
+	}
+}
+
+// New creates a queue, loading its file if one was given
+func New[T any](opts ...Option) (*Queue[T], error) {
+	o := options{visibility: 30 * time.Second, maxAttempts: 5, clock: schedule.RealClock}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	q := &Queue[T]{
+		visibility:  o.visibility,
+		maxAttempts: o.maxAttempts,
+		clock:       o.clock,
+		file:        o.file,
+		st:          &state[T]{jobs: make(map[string]*Job[T])},
+		changed:     make(chan struct{}),
+	}
+	if q.file != "" {
+		if err := q.load(); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// Enqueue adds a job visible right away and returns its id
+func (q *Queue[T]) Enqueue(payload T) (string, error) {
+	return q.EnqueueDelayed(payload, 0)
+}
+
+// EnqueueDelayed adds a job that becomes visible after delay
+func (q *Queue[T]) EnqueueDelayed(payload T, delay time.Duration) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", ErrClosed
+	}
+	var id string
+	err := q.update(func(s *state[T]) error {
+		s.seq++
+		job := &Job[T]{
+			ID:      fmt.Sprintf("job-%d", s.seq),
+			Seq:     s.seq,
+			Payload: payload,
+			State:   Ready,
+			Until:   q.clock.Now().Add(delay),
+		}
+		s.jobs[job.ID] = job
+		id = job.ID
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Delivery is a job handed to a worker, which must Ack or Nack it before
+// the visibility timeout
+type Delivery[T any] struct {
+	ID      string
+	Payload T
+	Attempt int
+	q       *Queue[T]
+}
+
+// Ack marks the job as done and removes it
+func (d *Delivery[T]) Ack() error {
+	return d.q.ack(d)
+}
+
+// Nack reports a failure. The job is delivered again after delay, or moved
+// to the dead letters if it has no attempts left.
+func (d *Delivery[T]) Nack(reason error, delay time.Duration) error {
+	return d.q.nack(d, reason, delay)
+}
+
+// Receive waits for a visible job and delivers it
+func (q *Queue[T]) Receive(ctx context.Context) (*Delivery[T], error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrClosed
+		}
+		now := q.clock.Now()
+		d, err := q.deliver(now)
+		if d != nil || err != nil {
+			q.mu.Unlock()
+			return d, err
+		}
+		next := q.nextEvent(now)
+		changed := q.changed
+		q.mu.Unlock()
+
+		// sleep until something is added, acknowledged or expires
+		var timer schedule.Timer
+		if !next.IsZero() {
+			timer = q.clock.AfterFunc(next.Sub(now), q.notify)
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryReceive delivers a visible job if there is one
+func (q *Queue[T]) TryReceive() (*Delivery[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	return q.deliver(q.clock.Now())
+}
+
+// DeadLetters returns the jobs that ran out of attempts
+func (q *Queue[T]) DeadLetters() []Job[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var dead []Job[T]
+	for _, job := range q.view(q.clock.Now()).sorted() {
+		if job.State == Dead {
+			dead = append(dead, *job)
+		}
+	}
+	return dead
+}
+
+// Requeue gives a dead job a new set of attempts
+func (q *Queue[T]) Requeue(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	return q.update(func(s *state[T]) error {
+		q.expire(s, now)
+		job, ok := s.jobs[id]
+		if !ok || job.State != Dead {
+			return fmt.Errorf("jobqueue: %s is not a dead job", id)
+		}
+		job.State = Ready
+		job.Attempts = 0
+		job.Until = now
+		return nil
+	})
+}
+
+// Stats counts the jobs in each state
+func (q *Queue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	var s Stats
+	for _, job := range q.view(now).jobs {
+		switch {
+		case job.State == Dead:
+			s.Dead++
+		case job.State == InFlight:
+			s.InFlight++
+		case job.Until.After(now):
+			s.Delayed++
+		default:
+			s.Ready++
+		}
+	}
+	return s
+}
+
+// Close wakes up waiting receivers, which return ErrClosed
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.wake()
+	}
+}
+
+// errNoJob tells update that deliver found nothing to change
+var errNoJob = errors.New("jobqueue: no visible job")
+
+// deliver hands out the oldest visible job. q.mu must be held.
+func (q *Queue[T]) deliver(now time.Time) (*Delivery[T], error) {
+	var d *Delivery[T]
+	err := q.update(func(s *state[T]) error {
+		q.expire(s, now)
+		for _, job := range s.sorted() {
+			if job.State == Ready && !job.Until.After(now) {
+				job.State = InFlight
+				job.Attempts++
+				job.Until = now.Add(q.visibility)
+				d = &Delivery[T]{ID: job.ID, Payload: job.Payload, Attempt: job.Attempts, q: q}
+				return nil
+			}
+		}
+		return errNoJob
+	})
+	switch {
+	case err == errNoJob:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return d, nil
+}
+
+// expire takes back the deliveries of s past their visibility timeout
+func (q *Queue[T]) expire(s *state[T], now time.Time) {
+	for _, job := range s.jobs {
+		if job.State == InFlight && !job.Until.After(now) {
+			q.retry(job, "visibility timeout expired", now)
+		}
+	}
+}
+
+// view returns the state as of now, with expired deliveries taken back.
+// Nothing is saved: expiring again after a reload gives the same result.
+// q.mu must be held.
+func (q *Queue[T]) view(now time.Time) *state[T] {
+	s := q.st.clone()
+	q.expire(s, now)
+	return s
+}
+
+// retry makes the job ready again at the given time, or dead
+func (q *Queue[T]) retry(job *Job[T], reason string, at time.Time) {
+	job.LastError = reason
+	if q.maxAttempts > 0 && job.Attempts >= q.maxAttempts {
+		job.State = Dead
+		return
+	}
+	job.State = Ready
+	job.Until = at
+}
+
+func (q *Queue[T]) ack(d *Delivery[T]) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	return q.update(func(s *state[T]) error {
+		q.expire(s, now)
+		job, ok := s.jobs[d.ID]
+		if !ok || job.State != InFlight || job.Attempts != d.Attempt {
+			return ErrNotInFlight
+		}
+		delete(s.jobs, d.ID)
+		return nil
+	})
+}
+
+func (q *Queue[T]) nack(d *Delivery[T], reason error, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	return q.update(func(s *state[T]) error {
+		q.expire(s, now)
+		job, ok := s.jobs[d.ID]
+		if !ok || job.State != InFlight || job.Attempts != d.Attempt {
+			return ErrNotInFlight
+		}
+		msg := "nack"
+		if reason != nil {
+			msg = reason.Error()
+		}
+		q.retry(job, msg, now.Add(delay))
+		return nil
+	})
+}
+
+// nextEvent returns when a delayed job appears or a delivery expires. q.mu
+// must be held.
+func (q *Queue[T]) nextEvent(now time.Time) time.Time {
+	var next time.Time
+	for _, job := range q.view(now).jobs {
+		if job.State == Dead {
+			continue
+		}
+		if next.IsZero() || job.Until.Before(next) {
+			next = job.Until
+		}
+	}
+	return next
+}
+
+// notify wakes up the receivers
+func (q *Queue[T]) notify() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.wake()
+}
+
+// wake is notify with q.mu held
+func (q *Queue[T]) wake() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// update applies fn to a copy of the state and keeps it only if fn
+// succeeds and the copy is saved, so a failed save leaves the queue as it
+// was. Receivers are woken up after a change. q.mu must be held.
+func (q *Queue[T]) update(fn func(s *state[T]) error) error {
+	s := q.st.clone()
+	if err := fn(s); err != nil {
+		return err
+	}
+	if q.file != "" {
+		if err := q.save(s); err != nil {
+			return err
+		}
+	}
+	q.st = s
+	q.wake()
+	return nil
+}
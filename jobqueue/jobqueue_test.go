@@ -0,0 +1,218 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+var start = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+func newQueue(t *testing.T, clock *schedule.FakeClock, opts ...Option) *Queue[string] {
+	t.Helper()
+	opts = append([]Option{WithClock(clock), Visibility(time.Minute), MaxAttempts(2)}, opts...)
+	q, err := New[string](opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func receive(t *testing.T, q *Queue[string]) *Delivery[string] {
+	t.Helper()
+	d, err := q.TryReceive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d == nil {
+		t.Fatal("no job delivered")
+	}
+	return d
+}
+
+func TestAckNack(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	q := newQueue(t, clock)
+	q.Enqueue("a")
+	q.Enqueue("b")
+
+	a := receive(t, q)
+	if a.Payload != "a" || a.Attempt != 1 {
+		t.Fatalf("got %+v, want a first", a)
+	}
+	if err := a.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Ack(); !errors.Is(err, ErrNotInFlight) {
+		t.Errorf("second Ack: %v", err)
+	}
+
+	b := receive(t, q)
+	if err := b.Nack(errors.New("busy"), 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := q.TryReceive(); d != nil {
+		t.Fatalf("nacked job delivered before its delay: %+v", d)
+	}
+	if s := q.Stats(); s != (Stats{Delayed: 1}) {
+		t.Errorf("stats %+v", s)
+	}
+	clock.Advance(10 * time.Second)
+	b = receive(t, q)
+	if b.Payload != "b" || b.Attempt != 2 {
+		t.Errorf("got %+v, want b again", b)
+	}
+
+	// out of attempts
+	b.Nack(errors.New("still busy"), 0)
+	dead := q.DeadLetters()
+	if len(dead) != 1 || dead[0].ID != b.ID || dead[0].LastError != "still busy" {
+		t.Fatalf("dead letters %+v", dead)
+	}
+	if err := q.Requeue(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if b = receive(t, q); b.Attempt != 1 {
+		t.Errorf("requeued job on attempt %d", b.Attempt)
+	}
+	if err := q.Requeue("job-1"); err == nil {
+		t.Error("requeued a job that is not dead")
+	}
+}
+
+func TestVisibilityExpires(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	q := newQueue(t, clock)
+	q.Enqueue("a")
+
+	first := receive(t, q)
+	clock.Advance(59 * time.Second)
+	if d, _ := q.TryReceive(); d != nil {
+		t.Fatal("delivered again before the visibility timeout")
+	}
+	clock.Advance(time.Second)
+	if s := q.Stats(); s != (Stats{Ready: 1}) {
+		t.Errorf("stats after the timeout %+v", s)
+	}
+	second := receive(t, q)
+	if second.ID != first.ID || second.Attempt != 2 {
+		t.Errorf("got %+v", second)
+	}
+	// the late worker lost its delivery
+	if err := first.Ack(); !errors.Is(err, ErrNotInFlight) {
+		t.Errorf("late Ack: %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	if dead := q.DeadLetters(); len(dead) != 1 || dead[0].LastError != "visibility timeout expired" {
+		t.Errorf("dead letters %+v", dead)
+	}
+}
+
+func TestReceiveWaits(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	q := newQueue(t, clock)
+	q.EnqueueDelayed("later", time.Hour)
+
+	got := make(chan *Delivery[string], 1)
+	go func() {
+		d, err := q.Receive(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		got <- d
+	}()
+	for clock.Pending() == 0 {
+		time.Sleep(100 * time.Microsecond)
+	}
+	clock.Advance(time.Hour)
+	if d := <-got; d == nil || d.Payload != "later" {
+		t.Errorf("got %+v", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.Receive(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled Receive: %v", err)
+	}
+	q.Close()
+	if _, err := q.Receive(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Receive after Close: %v", err)
+	}
+	if _, err := q.Enqueue("x"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Enqueue after Close: %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "queue.json")
+	clock := schedule.NewFakeClock(start)
+	q := newQueue(t, clock, File(file))
+	for _, p := range []string{"a", "b", "c"} {
+		if _, err := q.Enqueue(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive(t, q).Ack()
+	inFlight := receive(t, q)
+
+	// the program restarts while b is in flight
+	q = newQueue(t, clock, File(file))
+	if s := q.Stats(); s != (Stats{Ready: 1, InFlight: 1}) {
+		t.Fatalf("stats after reopening %+v", s)
+	}
+	if d := receive(t, q); d.Payload != "c" {
+		t.Errorf("got %q, want c", d.Payload)
+	}
+	clock.Advance(time.Minute)
+	d := receive(t, q)
+	if d.ID != inFlight.ID || d.Attempt != 2 {
+		t.Errorf("got %+v, want b delivered again", d)
+	}
+	// ids keep growing after a reload
+	if id, _ := q.Enqueue("d"); id != "job-4" {
+		t.Errorf("new job %s, want job-4", id)
+	}
+}
+
+func TestFailedSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "queue")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "queue.json")
+	clock := schedule.NewFakeClock(start)
+	q := newQueue(t, clock, File(file))
+	q.Enqueue("a")
+
+	// saving fails while the directory is gone
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue("b"); err == nil {
+		t.Error("Enqueue saved without a directory")
+	}
+	if d, err := q.TryReceive(); err == nil || d != nil {
+		t.Errorf("TryReceive = %+v, %v, want an error", d, err)
+	}
+	// nothing changed in memory either
+	if s := q.Stats(); s != (Stats{Ready: 1}) {
+		t.Errorf("stats after failed saves %+v", s)
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	d := receive(t, q)
+	if d.Payload != "a" || d.Attempt != 1 {
+		t.Errorf("got %+v, want the first attempt of a", d)
+	}
+	if id, _ := q.Enqueue("b"); id != "job-2" {
+		t.Errorf("new job %s, want job-2", id)
+	}
+}
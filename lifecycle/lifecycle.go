@@ -0,0 +1,201 @@
+// Package lifecycle starts the parts of a program in dependency order and
+// stops them in reverse order when the program is interrupted.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Component is a part of the program with start and stop hooks. Either hook
+// may be nil.
+type Component struct {
+	Name string
+	// Start must return once the component is running
+	Start func(ctx context.Context) error
+	// Stop must return once the component has stopped. ctx carries the
+	// deadline of the whole shutdown.
+	Stop func(ctx context.Context) error
+	// DependsOn names the components that must start before this one and
+	// stop after it
+	DependsOn []string
+}
+
+// StopError tells which components did not stop cleanly
+type StopError struct {
+	Failed   map[string]error // Stop returned an error
+	TimedOut []string         // still stopping, or not yet asked to, at the deadline
+}
+
+func (e *StopError) Error() string {
+	var parts []string
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Failed[name]))
+	}
+	if len(e.TimedOut) > 0 {
+		parts = append(parts, "did not stop in time: "+strings.Join(e.TimedOut, ", "))
+	}
+	return "lifecycle: " + strings.Join(parts, "; ")
+}
+
+// Manager owns a set of components
+type Manager struct {
+	stopTimeout time.Duration
+	components  map[string]Component
+	order       []string // registration order, to keep ties stable
+	started     []Component
+}
+
+// New creates a manager giving all components together stopTimeout to stop
+func New(stopTimeout time.Duration) *Manager {
+	return &Manager{
+		stopTimeout: stopTimeout,
+		components:  make(map[string]Component),
+	}
+}
+
+// Register adds a component. Names must be unique.
+func (m *Manager) Register(c Component) error {
+	if _, ok := m.components[c.Name]; ok {
+		return fmt.Errorf("lifecycle: component %q registered twice", c.Name)
+	}
+	m.components[c.Name] = c
+	m.order = append(m.order, c.Name)
+	return nil
+}
+
+// sorted returns the components so that dependencies come first
+func (m *Manager) sorted() ([]Component, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var list []Component
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		c, ok := m.components[name]
+		if !ok {
+			return fmt.Errorf("lifecycle: %s depends on unknown component %q", path[len(path)-1], name)
+		}
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle %s -> %s", strings.Join(path, " -> "), name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		next := append(append([]string(nil), path...), name)
+		for _, dep := range c.DependsOn {
+			if err := visit(dep, next); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		list = append(list, c)
+		return nil
+	}
+	for _, name := range m.order {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Start starts every component in dependency order. If one fails, the ones
+// already started are stopped again.
+func (m *Manager) Start(ctx context.Context) error {
+	list, err := m.sorted()
+	if err != nil {
+		return err
+	}
+	for _, c := range list {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				err = fmt.Errorf("lifecycle: starting %s: %w", c.Name, err)
+				return errors.Join(err, m.Stop())
+			}
+		}
+		m.started = append(m.started, c)
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order within the stop
+// timeout. It returns a *StopError if some of them failed or took too long.
+// The components not reached by the deadline are still stopped, in the
+// background and with a canceled context, so they know to hurry.
+func (m *Manager) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.stopTimeout)
+	defer cancel()
+
+	serr := &StopError{Failed: make(map[string]error)}
+	var late []Component
+	for i := len(m.started) - 1; i >= 0; i-- {
+		c := m.started[i]
+		if ctx.Err() != nil {
+			serr.TimedOut = append(serr.TimedOut, c.Name)
+			if c.Stop != nil {
+				late = append(late, c)
+			}
+			continue
+		}
+		if c.Stop == nil {
+			continue
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- c.Stop(ctx)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				serr.Failed[c.Name] = err
+			}
+		case <-ctx.Done():
+			serr.TimedOut = append(serr.TimedOut, c.Name)
+		}
+	}
+	m.started = nil
+	if len(late) > 0 {
+		go func() {
+			for _, c := range late {
+				c.Stop(ctx)
+			}
+		}()
+	}
+
+	if len(serr.Failed) == 0 && len(serr.TimedOut) == 0 {
+		return nil
+	}
+	return serr
+}
+
+// Run starts the components, waits for SIGINT, SIGTERM or the end of ctx,
+// and stops them
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	// a second signal kills the program as usual
+	stop()
+	return m.Stop()
+}
@@ -0,0 +1,159 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder logs the start and stop calls of components
+type recorder struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (r *recorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, s)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.log, " ")
+}
+
+func (r *recorder) component(name string, deps ...string) Component {
+	return Component{
+		Name:      name,
+		Start:     func(context.Context) error { r.add("+" + name); return nil },
+		Stop:      func(context.Context) error { r.add("-" + name); return nil },
+		DependsOn: deps,
+	}
+}
+
+func TestOrder(t *testing.T) {
+	var r recorder
+	m := New(time.Second)
+	// registered before their dependencies
+	for _, c := range []Component{r.component("api", "db", "cache"), r.component("cache", "db"), r.component("db"), r.component("metrics")} {
+		if err := m.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "+db +cache +api +metrics -metrics -api -cache -db"; r.String() != want {
+		t.Errorf("got %s, want %s", r.String(), want)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	m := New(time.Second)
+	m.Register(Component{Name: "db"})
+	if err := m.Register(Component{Name: "db"}); err == nil {
+		t.Error("registered db twice")
+	}
+}
+
+func TestDependencyErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []Component
+		want       string
+	}{
+		{"cycle", []Component{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"c"}}, {Name: "c", DependsOn: []string{"a"}}}, "cycle a -> b -> c -> a"},
+		{"self", []Component{{Name: "a", DependsOn: []string{"a"}}}, "cycle a -> a"},
+		{"unknown", []Component{{Name: "a", DependsOn: []string{"ghost"}}}, `a depends on unknown component "ghost"`},
+	}
+	for _, tt := range tests {
+		m := New(time.Second)
+		started := false
+		for _, c := range tt.components {
+			c.Start = func(context.Context) error { started = true; return nil }
+			m.Register(c)
+		}
+		err := m.Start(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+		if started {
+			t.Errorf("%s: a component started", tt.name)
+		}
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	var r recorder
+	m := New(time.Second)
+	boom := errors.New("boom")
+	m.Register(r.component("db"))
+	m.Register(Component{Name: "api", DependsOn: []string{"db"}, Start: func(context.Context) error { return boom }})
+	m.Register(r.component("worker", "api"))
+	if err := m.Start(context.Background()); !errors.Is(err, boom) {
+		t.Errorf("got %v, want boom", err)
+	}
+	if want := "+db -db"; r.String() != want {
+		t.Errorf("got %s, want %s", r.String(), want)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	late := make(chan error, 1)
+	m := New(20 * time.Millisecond)
+	m.Register(Component{
+		Name: "db",
+		Stop: func(ctx context.Context) error {
+			late <- ctx.Err()
+			return nil
+		},
+	})
+	m.Register(Component{Name: "cache", DependsOn: []string{"db"}})
+	m.Register(Component{
+		Name:      "api",
+		DependsOn: []string{"cache"},
+		Stop: func(ctx context.Context) error {
+			// ignores the deadline
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		},
+	})
+	m.Register(Component{Name: "bad", Stop: func(context.Context) error { return errors.New("stuck") }})
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Now()
+	err := m.Stop()
+	if elapsed := time.Since(t0); elapsed > 90*time.Millisecond {
+		t.Errorf("Stop waited %v past the deadline", elapsed)
+	}
+	var serr *StopError
+	if !errors.As(err, &serr) {
+		t.Fatalf("got %v, want a *StopError", err)
+	}
+	if serr.Failed["bad"] == nil || len(serr.Failed) != 1 {
+		t.Errorf("failed %v", serr.Failed)
+	}
+	if got := strings.Join(serr.TimedOut, " "); got != "api cache db" {
+		t.Errorf("timed out %q, want api cache db", got)
+	}
+
+	// db is still asked to stop, with an expired context
+	select {
+	case err := <-late:
+		if err == nil {
+			t.Error("db stopped with a live context")
+		}
+	case <-time.After(time.Second):
+		t.Error("db was never asked to stop")
+	}
+}
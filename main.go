@@ -1,14 +1,83 @@
 package main
 
-import "bitbucket.org/feliposz/go-by-example/examples"
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/diag"
+	"bitbucket.org/feliposz/go-by-example/errs"
+	"bitbucket.org/feliposz/go-by-example/examples"
+	"bitbucket.org/feliposz/go-by-example/stores"
+)
+
+var (
+	timeout  = flag.Duration("timeout", 0, "abort when a group of examples runs longer than this (0 means no limit)")
+	diagnose = flag.Bool("diagnose", false, "print a goroutine report when a group of examples times out")
+	stuck    = flag.Duration("stuck", 2*time.Second, "how long a goroutine must be blocked to be reported as stuck")
+
+	renderDir      = flag.String("render-dir", "", "save the pictures of the render example in this directory")
+	maxConcurrency = flag.Int("max-concurrency", 0, "run multipleExample with at most this many goroutines at once (0 skips it)")
+
+	benchStores = flag.Bool("bench-stores", false, "benchmark the shared map strategies instead of running the examples")
+	benchtime   = flag.Duration("benchtime", 200*time.Millisecond, "time spent on each store and scenario by -bench-stores")
+)
+
+var monitor *diag.Monitor
+
+// run calls the example group, watching for the timeout if one was given
+func run(name string, f func()) {
+	if *timeout <= 0 {
+		f()
+		return
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		f()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(*timeout):
+		err := errs.New(errs.DeadlineExceeded, "timed out", "group", name, "after", *timeout)
+		fmt.Fprintf(os.Stderr, "\n%v\n", err)
+		if monitor != nil {
+			monitor.Check().WriteTo(os.Stderr)
+		}
+		os.Exit(errs.ExitCode(err))
+	}
+}
 
 func main() {
-	examples.BasicExamples()
-	examples.FuncExamples()
-	examples.StructExamples()
-	examples.ErrorExamples()
-	examples.ConcurrentExamples()
-	examples.DataExamples()
-	examples.CollectionExamples()
-	examples.StringExamples()
+	flag.Parse()
+	examples.MaxConcurrency = *maxConcurrency
+	examples.RenderDir = *renderDir
+
+	if *benchStores {
+		results, err := stores.Run(stores.All, stores.DefaultScenarios(), *benchtime)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		stores.PrintReport(os.Stdout, results)
+		return
+	}
+
+	if *diagnose {
+		monitor = diag.NewMonitor(*stuck/4, *stuck)
+		monitor.Start()
+		defer monitor.Stop()
+	}
+
+	run("BasicExamples", examples.BasicExamples)
+	run("FuncExamples", examples.FuncExamples)
+	run("StructExamples", examples.StructExamples)
+	run("ErrorExamples", examples.ErrorExamples)
+	run("ConcurrentExamples", examples.ConcurrentExamples)
+	run("DataExamples", examples.DataExamples)
+	run("CollectionExamples", examples.CollectionExamples)
+	run("StringExamples", examples.StringExamples)
 }
@@ -0,0 +1,229 @@
+// Package mux receives from a changing set of channels, like a select
+// statement whose cases are only known at run time.
+package mux
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoSources is returned when there is no channel left to receive from
+	ErrNoSources = errors.New("mux: no sources")
+	// ErrTimeout is returned by RecvTimeout when nothing arrived in time
+	ErrTimeout = errors.New("mux: timeout")
+	// ErrNotReady is returned by TryRecv when no channel is ready
+	ErrNotReady = errors.New("mux: no source ready")
+)
+
+// Message is a value received from the source with the given label
+type Message[T any] struct {
+	Label string
+	Value T
+}
+
+type source[T any] struct {
+	label string
+	ch    <-chan T
+}
+
+// SourceStats tells how many messages came from one source
+type SourceStats struct {
+	Label    string
+	Received uint64
+	Share    float64 // fraction of all received messages
+	Closed   bool
+}
+
+// Mux multiplexes labeled channels. Closed channels are removed
+// automatically.
+type Mux[T any] struct {
+	mu      sync.Mutex
+	sources []*source[T]
+	changed chan struct{}
+	counts  map[string]uint64
+	closed  map[string]bool
+}
+
+// New creates a mux without sources
+func New[T any]() *Mux[T] {
+	return &Mux[T]{
+		changed: make(chan struct{}),
+		counts:  make(map[string]uint64),
+		closed:  make(map[string]bool),
+	}
+}
+
+// Add starts receiving from ch. A source with the same label is replaced.
+// Receivers already waiting pick up the change.
+func (m *Mux[T]) Add(label string, ch <-chan T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.add(label, ch)
+}
+
+// add registers ch under label. m.mu must be held.
+func (m *Mux[T]) add(label string, ch <-chan T) {
+	s := &source[T]{label: label, ch: ch}
+	delete(m.closed, label)
+	for i, old := range m.sources {
+		if old.label == label {
+			m.sources[i] = s
+			m.notify()
+			return
+		}
+	}
+	m.sources = append(m.sources, s)
+	m.notify()
+}
+
+// Remove stops receiving from the source with the given label
+func (m *Mux[T]) Remove(label string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sources {
+		if s.label == label {
+			m.remove(s)
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of sources
+func (m *Mux[T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sources)
+}
+
+// remove drops s if it is still registered and tells whether it was. m.mu
+// must be held.
+func (m *Mux[T]) remove(s *source[T]) bool {
+	for i, other := range m.sources {
+		if other == s {
+			m.sources = append(m.sources[:i], m.sources[i+1:]...)
+			m.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// notify wakes up receivers so they rebuild their cases. m.mu must be held.
+func (m *Mux[T]) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// Recv blocks until a source sends a value or ctx is done
+func (m *Mux[T]) Recv(ctx context.Context) (Message[T], error) {
+	return m.recv(ctx, nil, false)
+}
+
+// RecvTimeout is like Recv but gives up after d
+func (m *Mux[T]) RecvTimeout(d time.Duration) (Message[T], error) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	return m.recv(context.Background(), t.C, false)
+}
+
+// TryRecv returns a value only if one is ready right away
+func (m *Mux[T]) TryRecv() (Message[T], error) {
+	return m.recv(context.Background(), nil, true)
+}
+
+func (m *Mux[T]) recv(ctx context.Context, timeout <-chan time.Time, nonBlocking bool) (Message[T], error) {
+	var zero Message[T]
+	for {
+		m.mu.Lock()
+		sources := append([]*source[T](nil), m.sources...)
+		changed := m.changed
+		m.mu.Unlock()
+		if len(sources) == 0 {
+			return zero, ErrNoSources
+		}
+
+		cases := make([]reflect.SelectCase, 0, len(sources)+4)
+		for _, s := range sources {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.ch)})
+		}
+		changedCase := len(cases)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(changed)})
+		doneCase := -1
+		if done := ctx.Done(); done != nil {
+			doneCase = len(cases)
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+		}
+		timeoutCase := -1
+		if timeout != nil {
+			timeoutCase = len(cases)
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timeout)})
+		}
+		if nonBlocking {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+		}
+
+		chosen, v, ok := reflect.Select(cases)
+		switch {
+		case chosen < len(sources):
+			s := sources[chosen]
+			m.mu.Lock()
+			if !ok {
+				// a replaced or removed source must not mark the label of
+				// its successor closed
+				if m.remove(s) {
+					m.closed[s.label] = true
+				}
+				m.mu.Unlock()
+				continue
+			}
+			m.counts[s.label]++
+			m.mu.Unlock()
+			// the check only fails for a nil interface value, which stays zero
+			value, _ := v.Interface().(T)
+			return Message[T]{Label: s.label, Value: value}, nil
+		case chosen == changedCase:
+			continue
+		case chosen == doneCase:
+			return zero, ctx.Err()
+		case chosen == timeoutCase:
+			return zero, ErrTimeout
+		default:
+			return zero, ErrNotReady
+		}
+	}
+}
+
+// Stats returns the number of messages received per source, including the
+// ones already closed or removed, sorted by label. With every source always
+// ready, shares close to each other mean the selection is fair.
+func (m *Mux[T]) Stats() []SourceStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	labels := make(map[string]bool)
+	var total uint64
+	for label, n := range m.counts {
+		labels[label] = true
+		total += n
+	}
+	for _, s := range m.sources {
+		labels[s.label] = true
+	}
+	stats := make([]SourceStats, 0, len(labels))
+	for label := range labels {
+		st := SourceStats{Label: label, Received: m.counts[label], Closed: m.closed[label]}
+		if total > 0 {
+			st.Share = float64(st.Received) / float64(total)
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Label < stats[j].Label
+	})
+	return stats
+}
@@ -0,0 +1,107 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecv(t *testing.T) {
+	m := New[int]()
+	a, b := make(chan int, 1), make(chan int, 1)
+	m.Add("a", a)
+	m.Add("b", b)
+	b <- 2
+	msg, err := m.Recv(context.Background())
+	if err != nil || msg.Label != "b" || msg.Value != 2 {
+		t.Errorf("got %+v, %v, want b 2", msg, err)
+	}
+	if _, err := m.TryRecv(); err != ErrNotReady {
+		t.Errorf("TryRecv: got %v, want %v", err, ErrNotReady)
+	}
+	if _, err := m.RecvTimeout(time.Millisecond); err != ErrTimeout {
+		t.Errorf("RecvTimeout: got %v, want %v", err, ErrTimeout)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Recv(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Recv: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestClosedSourcesRemoved(t *testing.T) {
+	m := New[int]()
+	a := make(chan int, 1)
+	m.Add("a", a)
+	a <- 1
+	close(a)
+	if msg, err := m.Recv(context.Background()); err != nil || msg.Value != 1 {
+		t.Errorf("got %+v, %v", msg, err)
+	}
+	if _, err := m.Recv(context.Background()); err != ErrNoSources {
+		t.Errorf("got %v, want %v", err, ErrNoSources)
+	}
+	st := m.Stats()
+	if len(st) != 1 || st[0] != (SourceStats{Label: "a", Received: 1, Share: 1, Closed: true}) {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestAddWakesReceiver(t *testing.T) {
+	m := New[string]()
+	m.Add("idle", make(chan string))
+	got := make(chan Message[string])
+	go func() {
+		msg, _ := m.Recv(context.Background())
+		got <- msg
+	}()
+	late := make(chan string, 1)
+	late <- "hi"
+	m.Add("late", late)
+	if msg := <-got; msg.Label != "late" || msg.Value != "hi" {
+		t.Errorf("got %+v", msg)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	m := New[int]()
+	old := make(chan int)
+	m.Add("a", old)
+	got := make(chan Message[int])
+	go func() {
+		msg, _ := m.Recv(context.Background())
+		got <- msg
+	}()
+	// let the receiver wait on old, then make it see old closed only after
+	// the label was given a new channel
+	time.Sleep(20 * time.Millisecond)
+	fresh := make(chan int, 1)
+	m.mu.Lock()
+	close(old)
+	time.Sleep(20 * time.Millisecond)
+	m.add("a", fresh)
+	m.mu.Unlock()
+
+	fresh <- 7
+	if msg := <-got; msg.Label != "a" || msg.Value != 7 {
+		t.Errorf("got %+v, want a 7", msg)
+	}
+	if m.Len() != 1 {
+		t.Errorf("%d sources, want 1", m.Len())
+	}
+	if st := m.Stats(); len(st) != 1 || st[0].Closed {
+		t.Errorf("the old channel marked the new source closed: %+v", st)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	m := New[int]()
+	m.Add("a", make(chan int))
+	if !m.Remove("a") || m.Remove("a") {
+		t.Error("Remove reported the wrong result")
+	}
+	if _, err := m.TryRecv(); err != ErrNoSources {
+		t.Errorf("got %v, want %v", err, ErrNoSources)
+	}
+}
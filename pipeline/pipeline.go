@@ -0,0 +1,390 @@
+// Package pipeline connects goroutines with typed channels.
+//
+// Every stage runs in its own goroutine(s), reads from the channel returned by
+// the previous stage and writes to a new one. Errors from any stage flow to a
+// single error channel, and the first error cancels the whole pipeline so that
+// upstream stages stop producing.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Pipeline holds the shared state of a set of connected stages
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	started bool // Errors was called, no more stages can be added
+	errIn   chan error
+	errc    chan error
+}
+
+// New creates a pipeline bound to ctx. Canceling ctx stops every stage.
+// Either Wait or Errors must be called once all stages were added; adding a
+// stage afterwards panics.
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pipeline{
+		ctx:    ctx,
+		cancel: cancel,
+		errIn:  make(chan error),
+		errc:   make(chan error),
+	}
+	go p.forward()
+	return p
+}
+
+// Context returns the context shared by all stages
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Cancel stops all stages
+func (p *Pipeline) Cancel() {
+	p.cancel()
+}
+
+// Errors returns the channel where stage errors are delivered. It is closed
+// after every stage has finished.
+func (p *Pipeline) Errors() <-chan error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		p.started = true
+		go func() {
+			p.wg.Wait()
+			close(p.errIn)
+			p.cancel()
+		}()
+	}
+	return p.errc
+}
+
+// Wait blocks until all stages finish and returns their errors joined
+func (p *Pipeline) Wait() error {
+	var errs []error
+	for err := range p.Errors() {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// forward queues errors so that a failing stage never blocks on reporting
+func (p *Pipeline) forward() {
+	var queue []error
+	in := p.errIn
+	for in != nil || len(queue) > 0 {
+		var out chan<- error
+		var next error
+		if len(queue) > 0 {
+			out = p.errc
+			next = queue[0]
+		}
+		select {
+		case err, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			queue = append(queue, err)
+		case out <- next:
+			queue = queue[1:]
+		}
+	}
+	close(p.errc)
+}
+
+// fail reports err and cancels the pipeline
+func (p *Pipeline) fail(err error) {
+	p.cancel()
+	p.errIn <- err
+}
+
+// run starts workers copies of work and calls done once all of them return.
+// It panics once the pipeline was started: the error channel may already be
+// closed, and a failing stage would have nowhere to report.
+func (p *Pipeline) run(workers int, work func(), done func()) {
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		panic("pipeline: stage added after Errors or Wait")
+	}
+	p.wg.Add(workers + 1)
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			defer wg.Done()
+			work()
+		}()
+	}
+	go func() {
+		defer p.wg.Done()
+		wg.Wait()
+		done()
+	}()
+}
+
+// Option configures a single stage
+type Option func(*config)
+
+type config struct {
+	buffer  int
+	workers int
+}
+
+// Buffer sets the capacity of the output channel of a stage
+func Buffer(n int) Option {
+	return func(c *config) {
+		c.buffer = n
+	}
+}
+
+// Workers sets how many goroutines run a stage in parallel. Output order is
+// not preserved when n > 1.
+func Workers(n int) Option {
+	return func(c *config) {
+		c.workers = n
+	}
+}
+
+func configure(opts []Option) config {
+	c := config{workers: 1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.buffer < 0 {
+		c.buffer = 0
+	}
+	if c.workers < 1 {
+		c.workers = 1
+	}
+	return c
+}
+
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func recv[T any](ctx context.Context, in <-chan T) (v T, ok bool) {
+	select {
+	case v, ok = <-in:
+		return v, ok
+	case <-ctx.Done():
+		return v, false
+	}
+}
+
+// Source runs gen and emits every value it produces. emit returns false once
+// the pipeline was canceled and gen should stop.
+func Source[T any](p *Pipeline, gen func(ctx context.Context, emit func(T) bool) error, opts ...Option) <-chan T {
+	cfg := configure(opts)
+	out := make(chan T, cfg.buffer)
+	emit := func(v T) bool {
+		return send(p.ctx, out, v)
+	}
+	p.run(1, func() {
+		if err := gen(p.ctx, emit); err != nil && !errors.Is(err, p.ctx.Err()) {
+			p.fail(err)
+		}
+	}, func() {
+		close(out)
+	})
+	return out
+}
+
+// FromSlice emits the given items in order
+func FromSlice[T any](p *Pipeline, items []T, opts ...Option) <-chan T {
+	return Source(p, func(ctx context.Context, emit func(T) bool) error {
+		for _, v := range items {
+			if !emit(v) {
+				break
+			}
+		}
+		return nil
+	}, opts...)
+}
+
+// Map applies fn to every value. An error stops the pipeline.
+func Map[In, Out any](p *Pipeline, in <-chan In, fn func(context.Context, In) (Out, error), opts ...Option) <-chan Out {
+	cfg := configure(opts)
+	out := make(chan Out, cfg.buffer)
+	p.run(cfg.workers, func() {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return
+			}
+			r, err := fn(p.ctx, v)
+			if err != nil {
+				p.fail(err)
+				return
+			}
+			if !send(p.ctx, out, r) {
+				return
+			}
+		}
+	}, func() {
+		close(out)
+	})
+	return out
+}
+
+// Filter forwards only the values for which keep returns true
+func Filter[T any](p *Pipeline, in <-chan T, keep func(T) bool, opts ...Option) <-chan T {
+	cfg := configure(opts)
+	out := make(chan T, cfg.buffer)
+	p.run(cfg.workers, func() {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return
+			}
+			if keep(v) && !send(p.ctx, out, v) {
+				return
+			}
+		}
+	}, func() {
+		close(out)
+	})
+	return out
+}
+
+// Batch groups values into slices of up to size elements. The last batch may
+// be smaller.
+func Batch[T any](p *Pipeline, in <-chan T, size int, opts ...Option) <-chan []T {
+	cfg := configure(opts)
+	out := make(chan []T, cfg.buffer)
+	if size < 1 {
+		size = 1
+	}
+	p.run(1, func() {
+		batch := make([]T, 0, size)
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				break
+			}
+			batch = append(batch, v)
+			if len(batch) == size {
+				if !send(p.ctx, out, batch) {
+					return
+				}
+				batch = make([]T, 0, size)
+			}
+		}
+		if len(batch) > 0 && p.ctx.Err() == nil {
+			send(p.ctx, out, batch)
+		}
+	}, func() {
+		close(out)
+	})
+	return out
+}
+
+// FanOut splits in into n channels. Each value goes to exactly one of them,
+// whichever consumer is ready first.
+func FanOut[T any](p *Pipeline, in <-chan T, n int, opts ...Option) []<-chan T {
+	cfg := configure(opts)
+	outs := make([]<-chan T, n)
+	for i := range outs {
+		out := make(chan T, cfg.buffer)
+		outs[i] = out
+		p.run(1, func() {
+			for {
+				v, ok := recv(p.ctx, in)
+				if !ok || !send(p.ctx, out, v) {
+					return
+				}
+			}
+		}, func() {
+			close(out)
+		})
+	}
+	return outs
+}
+
+// Merge (fan-in) combines several channels into one. It is closed once all
+// inputs are closed.
+func Merge[T any](p *Pipeline, ins []<-chan T, opts ...Option) <-chan T {
+	cfg := configure(opts)
+	out := make(chan T, cfg.buffer)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		in := in
+		p.run(1, func() {
+			defer wg.Done()
+			for {
+				v, ok := recv(p.ctx, in)
+				if !ok || !send(p.ctx, out, v) {
+					return
+				}
+			}
+		}, func() {})
+	}
+	p.run(1, wg.Wait, func() {
+		close(out)
+	})
+	return out
+}
+
+// Tee copies every value into n channels. A slow consumer holds back the
+// others, so each output should be buffered or drained concurrently.
+func Tee[T any](p *Pipeline, in <-chan T, n int, opts ...Option) []<-chan T {
+	cfg := configure(opts)
+	chans := make([]chan T, n)
+	outs := make([]<-chan T, n)
+	for i := range chans {
+		chans[i] = make(chan T, cfg.buffer)
+		outs[i] = chans[i]
+	}
+	p.run(1, func() {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return
+			}
+			for _, out := range chans {
+				if !send(p.ctx, out, v) {
+					return
+				}
+			}
+		}
+	}, func() {
+		for _, out := range chans {
+			close(out)
+		}
+	})
+	return outs
+}
+
+// Sink consumes every value with fn. An error stops the pipeline.
+func Sink[T any](p *Pipeline, in <-chan T, fn func(context.Context, T) error, opts ...Option) {
+	cfg := configure(opts)
+	p.run(cfg.workers, func() {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return
+			}
+			if err := fn(p.ctx, v); err != nil {
+				p.fail(err)
+				return
+			}
+		}
+	}, func() {})
+}
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+)
+
+var errBad = errors.New("bad value")
+
+func collect[T any](p *Pipeline, in <-chan T) *[]T {
+	var got []T
+	Sink(p, in, func(_ context.Context, v T) error {
+		got = append(got, v)
+		return nil
+	})
+	return &got
+}
+
+func TestOrder(t *testing.T) {
+	p := New(context.Background())
+	nums := FromSlice(p, []int{1, 2, 3, 4, 5, 6, 7})
+	odd := Filter(p, nums, func(v int) bool { return v%2 == 1 })
+	squares := Map(p, odd, func(_ context.Context, v int) (int, error) { return v * v, nil })
+	got := collect(p, Batch(p, squares, 3))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{1, 9, 25}, {49}}
+	if len(*got) != len(want) {
+		t.Fatalf("got %v, want %v", *got, want)
+	}
+	for i := range want {
+		if !equal((*got)[i], want[i]) {
+			t.Errorf("batch %d: got %v, want %v", i, (*got)[i], want[i])
+		}
+	}
+}
+
+func TestFanOutMerge(t *testing.T) {
+	p := New(context.Background())
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	outs := FanOut(p, FromSlice(p, items), 4)
+	got := collect(p, Merge(p, outs))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// every value exactly once, in any order
+	sort.Ints(*got)
+	if !equal(*got, items) {
+		t.Errorf("got %v", *got)
+	}
+}
+
+func TestTee(t *testing.T) {
+	p := New(context.Background())
+	outs := Tee(p, FromSlice(p, []string{"a", "b"}), 2, Buffer(2))
+	a, b := collect(p, outs[0]), collect(p, outs[1])
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !equal(*a, []string{"a", "b"}) || !equal(*b, []string{"a", "b"}) {
+		t.Errorf("got %v and %v", *a, *b)
+	}
+}
+
+func TestErrorStops(t *testing.T) {
+	p := New(context.Background())
+	nums := Source(p, func(ctx context.Context, emit func(int) bool) error {
+		// endless, only the failure downstream stops it
+		for i := 0; ; i++ {
+			if !emit(i) {
+				return ctx.Err()
+			}
+		}
+	})
+	checked := Map(p, nums, func(_ context.Context, v int) (int, error) {
+		if v == 5 {
+			return 0, errBad
+		}
+		return v, nil
+	}, Workers(2))
+	collect(p, checked)
+	err := p.Wait()
+	if !errors.Is(err, errBad) {
+		t.Errorf("got %v, want %v", err, errBad)
+	}
+	if p.Context().Err() == nil {
+		t.Error("pipeline not canceled after the error")
+	}
+}
+
+func TestSeveralErrors(t *testing.T) {
+	p := New(context.Background())
+	fail := func(context.Context, int) error { return errBad }
+	Sink(p, FromSlice(p, []int{1}), fail)
+	Sink(p, FromSlice(p, []int{2}), fail)
+	var n int
+	for err := range p.Errors() {
+		if err != errBad {
+			t.Errorf("got %v, want %v", err, errBad)
+		}
+		n++
+	}
+	// the second sink may see the cancellation before its value
+	if n < 1 || n > 2 {
+		t.Errorf("got %d errors", n)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	nums := Source(p, func(ctx context.Context, emit func(int) bool) error {
+		for i := 0; emit(i); i++ {
+			if i == 10 {
+				cancel()
+			}
+		}
+		return ctx.Err()
+	})
+	collect(p, nums)
+	// the cancellation is not an error of a stage
+	if err := p.Wait(); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+}
+
+func TestStageAfterStart(t *testing.T) {
+	p := New(context.Background())
+	nums := FromSlice(p, []int{1, 2}, Buffer(2))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("adding a stage after Wait did not panic")
+		}
+	}()
+	Sink(p, nums, func(context.Context, int) error { return errBad })
+}
+
+func equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
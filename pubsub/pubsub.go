@@ -0,0 +1,283 @@
+// Package pubsub implements an in-process topic based message broker.
+//
+// Topics are dot separated words, like "chat.alice". Subscription patterns may
+// use "*" to match exactly one word and "#" (only as the last word) to match
+// any number of remaining words: "chat.*" matches "chat.alice" and "#" matches
+// every topic.
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy decides what happens when a subscriber queue is full
+type Policy int
+
+const (
+	// Block waits until the subscriber has room for the message
+	Block Policy = iota
+	// DropOldest discards the oldest queued message to make room
+	DropOldest
+	// DropNewest discards the message being published
+	DropNewest
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop oldest"
+	case DropNewest:
+		return "drop newest"
+	}
+	return "unknown"
+}
+
+// ErrBadPattern is returned by Subscribe for a "#" that is not the last word
+// of the pattern
+var ErrBadPattern = errors.New("pubsub: # must be the last word of a pattern")
+
+// Message is a payload published on a topic
+type Message[T any] struct {
+	Topic   string
+	Payload T
+}
+
+// Stats holds delivery counters
+type Stats struct {
+	Published   uint64 // messages passed to Publish
+	Delivered   uint64 // messages queued for a subscriber
+	Dropped     uint64 // messages discarded by an overflow policy
+	Unmatched   uint64 // messages that had no subscriber
+	Subscribers int    // current number of subscriptions
+}
+
+// Broker routes published messages to matching subscriptions
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+
+	published atomic.Uint64
+	unmatched atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// NewBroker creates an empty broker
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{subs: make(map[*Subscription[T]]struct{})}
+}
+
+// Subscribe registers interest in topics matching pattern. Messages are queued
+// in a buffer of the given size and overflow is handled according to policy.
+func (b *Broker[T]) Subscribe(pattern string, size int, policy Policy) (*Subscription[T], error) {
+	words := strings.Split(pattern, ".")
+	for i, w := range words {
+		if w == "#" && i < len(words)-1 {
+			return nil, fmt.Errorf("%w: %q", ErrBadPattern, pattern)
+		}
+	}
+	if size < 0 {
+		size = 0
+	}
+	if size == 0 && policy != Block {
+		// dropping policies need somewhere to hold a message
+		size = 1
+	}
+	ch := make(chan Message[T], size)
+	s := &Subscription[T]{
+		C:       ch,
+		pattern: words,
+		policy:  policy,
+		broker:  b,
+		ch:      ch,
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.close()
+		return s, nil
+	}
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Publish sends payload to every subscription matching topic and returns how
+// many of them received it
+func (b *Broker[T]) Publish(topic string, payload T) int {
+	b.published.Add(1)
+	msg := Message[T]{Topic: topic, Payload: payload}
+	words := strings.Split(topic, ".")
+
+	b.mu.RLock()
+	matched := make([]*Subscription[T], 0, len(b.subs))
+	for s := range b.subs {
+		if match(s.pattern, words) {
+			matched = append(matched, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(matched) == 0 {
+		b.unmatched.Add(1)
+		return 0
+	}
+	count := 0
+	for _, s := range matched {
+		if s.deliver(msg) {
+			count++
+		}
+	}
+	return count
+}
+
+// Unsubscribe removes s from the broker and closes its channel
+func (b *Broker[T]) Unsubscribe(s *Subscription[T]) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+	s.close()
+}
+
+// Close unsubscribes everybody. Later subscriptions are closed immediately.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = make(map[*Subscription[T]]struct{})
+	b.closed = true
+	b.mu.Unlock()
+	for s := range subs {
+		s.close()
+	}
+}
+
+// Stats returns the delivery counters of the broker
+func (b *Broker[T]) Stats() Stats {
+	b.mu.RLock()
+	n := len(b.subs)
+	b.mu.RUnlock()
+	return Stats{
+		Published:   b.published.Load(),
+		Delivered:   b.delivered.Load(),
+		Dropped:     b.dropped.Load(),
+		Unmatched:   b.unmatched.Load(),
+		Subscribers: n,
+	}
+}
+
+// Subscription is a queue of messages for one subscriber
+type Subscription[T any] struct {
+	// C receives the messages. It is closed after Unsubscribe.
+	C <-chan Message[T]
+
+	pattern []string
+	policy  Policy
+	broker  *Broker[T]
+
+	mu     sync.RWMutex
+	ch     chan Message[T]
+	done   chan struct{}
+	once   sync.Once
+	closed bool
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Unsubscribe removes the subscription from its broker
+func (s *Subscription[T]) Unsubscribe() {
+	s.broker.Unsubscribe(s)
+}
+
+// Stats returns the counters of this subscription only
+func (s *Subscription[T]) Stats() Stats {
+	return Stats{
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+	}
+}
+
+func (s *Subscription[T]) close() {
+	s.once.Do(func() {
+		// release publishers blocked on a full queue before taking the lock
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+func (s *Subscription[T]) deliver(msg Message[T]) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.ch <- msg:
+		default:
+			s.drop()
+			return false
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- msg:
+				s.count()
+				return true
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- msg:
+		case <-s.done:
+			return false
+		}
+	}
+	s.count()
+	return true
+}
+
+func (s *Subscription[T]) count() {
+	s.delivered.Add(1)
+	s.broker.delivered.Add(1)
+}
+
+func (s *Subscription[T]) drop() {
+	s.dropped.Add(1)
+	s.broker.dropped.Add(1)
+}
+
+// match tells if topic words satisfy the pattern words
+func match(pattern, topic []string) bool {
+	for i, p := range pattern {
+		if p == "#" {
+			return true
+		}
+		if i >= len(topic) {
+			return false
+		}
+		if p != "*" && p != topic[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"chat.alice", "chat.alice", true},
+		{"chat.alice", "chat.bob", false},
+		{"chat.*", "chat.alice", true},
+		{"chat.*", "chat", false},
+		{"chat.*", "chat.alice.typing", false},
+		{"*.alice", "chat.alice", true},
+		{"*", "chat", true},
+		{"chat.#", "chat", true},
+		{"chat.#", "chat.alice.typing", true},
+		{"chat.#", "news.alice", false},
+		{"*.#", "chat.alice", true},
+		{"#", "anything.at.all", true},
+		{"chat", "chat.alice", false},
+	}
+	for _, tt := range tests {
+		if got := match(strings.Split(tt.pattern, "."), strings.Split(tt.topic, ".")); got != tt.want {
+			t.Errorf("%s ~ %s: got %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestBadPattern(t *testing.T) {
+	b := NewBroker[string]()
+	for _, pattern := range []string{"#.alice", "chat.#.typing", "#.#"} {
+		if _, err := b.Subscribe(pattern, 1, Block); !errors.Is(err, ErrBadPattern) {
+			t.Errorf("%s: got %v, want %v", pattern, err, ErrBadPattern)
+		}
+	}
+	if n := b.Stats().Subscribers; n != 0 {
+		t.Errorf("%d subscribers after bad patterns", n)
+	}
+}
+
+func subscribe(t *testing.T, b *Broker[string], pattern string, size int, policy Policy) *Subscription[string] {
+	t.Helper()
+	s, err := b.Subscribe(pattern, size, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPublish(t *testing.T) {
+	b := NewBroker[string]()
+	alice := subscribe(t, b, "chat.alice", 4, Block)
+	all := subscribe(t, b, "chat.*", 4, Block)
+	if n := b.Publish("chat.alice", "hi"); n != 2 {
+		t.Errorf("delivered to %d, want 2", n)
+	}
+	if n := b.Publish("chat.bob", "yo"); n != 1 {
+		t.Errorf("delivered to %d, want 1", n)
+	}
+	if n := b.Publish("news", "x"); n != 0 {
+		t.Errorf("delivered to %d, want 0", n)
+	}
+	if msg := <-alice.C; msg.Topic != "chat.alice" || msg.Payload != "hi" {
+		t.Errorf("got %+v", msg)
+	}
+	if len(alice.C) != 0 || len(all.C) != 2 {
+		t.Errorf("queued %d and %d, want 0 and 2", len(alice.C), len(all.C))
+	}
+	want := Stats{Published: 3, Delivered: 3, Unmatched: 1, Subscribers: 2}
+	if got := b.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := NewBroker[string]()
+	s := subscribe(t, b, "#", 1, Block)
+	other := subscribe(t, b, "#", 2, Block)
+	b.Publish("a", "1")
+	s.Unsubscribe()
+	if n := b.Publish("a", "2"); n != 1 {
+		t.Errorf("delivered to %d after Unsubscribe, want 1", n)
+	}
+	// the queued message is still there, then the channel is closed
+	if msg, ok := <-s.C; !ok || msg.Payload != "1" {
+		t.Errorf("got %+v, %v", msg, ok)
+	}
+	if _, ok := <-s.C; ok {
+		t.Error("channel not closed")
+	}
+	s.Unsubscribe()
+	if n := b.Stats().Subscribers; n != 1 {
+		t.Errorf("%d subscribers, want 1", n)
+	}
+	<-other.C
+	<-other.C
+}
+
+func TestUnsubscribeReleasesPublisher(t *testing.T) {
+	b := NewBroker[string]()
+	s := subscribe(t, b, "#", 0, Block)
+	done := make(chan int)
+	go func() {
+		done <- b.Publish("a", "1")
+	}()
+	s.Unsubscribe()
+	if n := <-done; n != 0 {
+		t.Errorf("delivered to %d, want 0", n)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	tests := []struct {
+		policy  Policy
+		want    string
+		dropped uint64
+	}{
+		{DropOldest, "3 4", 2},
+		{DropNewest, "1 2", 2},
+	}
+	for _, tt := range tests {
+		b := NewBroker[string]()
+		s := subscribe(t, b, "#", 2, tt.policy)
+		for _, v := range []string{"1", "2", "3", "4"} {
+			b.Publish("a", v)
+		}
+		b.Close()
+		var got []string
+		for msg := range s.C {
+			got = append(got, msg.Payload)
+		}
+		if strings.Join(got, " ") != tt.want || s.Stats().Dropped != tt.dropped {
+			t.Errorf("%v: got %v and %d dropped, want %s and %d", tt.policy, got, s.Stats().Dropped, tt.want, tt.dropped)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := NewBroker[string]()
+	b.Close()
+	s := subscribe(t, b, "#", 1, Block)
+	if _, ok := <-s.C; ok {
+		t.Error("subscription after Close is open")
+	}
+	if n := b.Publish("a", "1"); n != 0 {
+		t.Errorf("delivered to %d after Close", n)
+	}
+}
@@ -0,0 +1,272 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// State of a circuit breaker
+type State int
+
+const (
+	// Closed lets every call through and counts failures
+	Closed State = iota
+	// Open rejects every call until the open timeout expires
+	Open
+	// HalfOpen lets a few trial calls through to probe the dependency
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// ErrOpen is returned instead of calling a dependency while the breaker is
+// open, or when all half-open trial slots are taken
+var ErrOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker trips open when the failure rate of recent calls reaches a
+// threshold
+type CircuitBreaker struct {
+	rate        float64
+	minRequests int
+	window      time.Duration
+	openTimeout time.Duration
+	trials      int
+	isFailure   func(error) bool
+	onChange    func(from, to State)
+	clock       Clock
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	buckets  []bucket
+	inFlight int // trial calls running in half-open state
+	passed   int // trial calls that succeeded in half-open state
+	gen      int // incremented on every transition
+}
+
+// bucket counts the calls of one slice of the rolling window
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+const bucketsPerWindow = 10
+
+// BreakerOption configures a circuit breaker
+type BreakerOption func(*CircuitBreaker)
+
+// FailureRate trips the breaker when at least rate (0 to 1) of the calls in
+// the window failed, once there were at least minRequests calls
+func FailureRate(rate float64, minRequests int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.rate = rate
+		b.minRequests = minRequests
+	}
+}
+
+// Window sets the length of the rolling window used to compute the rate
+func Window(d time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.window = d
+	}
+}
+
+// OpenTimeout sets how long the breaker stays open before probing again
+func OpenTimeout(d time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.openTimeout = d
+	}
+}
+
+// HalfOpenRequests sets how many trial calls must succeed to close again
+func HalfOpenRequests(n int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.trials = n
+	}
+}
+
+// IsFailure decides which errors count as failures (every non nil by default)
+func IsFailure(f func(error) bool) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.isFailure = f
+	}
+}
+
+// OnStateChange registers a callback called on every transition. It runs
+// with the breaker locked and must not call back into it.
+func OnStateChange(f func(from, to State)) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.onChange = f
+	}
+}
+
+// WithClock replaces the real clock
+func WithClock(c Clock) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.clock = c
+	}
+}
+
+// NewCircuitBreaker creates a closed breaker. By default it opens when half
+// of at least 10 calls in the last minute failed, and probes again after 30s.
+func NewCircuitBreaker(opts ...BreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{
+		rate:        0.5,
+		minRequests: 10,
+		window:      time.Minute,
+		openTimeout: 30 * time.Second,
+		trials:      1,
+		isFailure:   func(err error) bool { return err != nil },
+		clock:       RealClock,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.trials < 1 {
+		b.trials = 1
+	}
+	return b
+}
+
+// State returns the current state
+func (b *CircuitBreaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(b.clock.Now())
+	return b.state
+}
+
+// Execute calls fn unless the breaker is open, and records the outcome. A
+// panic in fn counts as a failure and goes on unwinding.
+func (b *CircuitBreaker) Execute(fn func() error) (err error) {
+	gen, err := b.before()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			b.after(gen, true)
+			panic(r)
+		}
+		b.after(gen, b.isFailure(err))
+	}()
+	return fn()
+}
+
+func (b *CircuitBreaker) before() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(b.clock.Now())
+	switch b.state {
+	case Open:
+		return b.gen, ErrOpen
+	case HalfOpen:
+		if b.inFlight+b.passed >= b.trials {
+			return b.gen, ErrOpen
+		}
+		b.inFlight++
+	}
+	return b.gen, nil
+}
+
+func (b *CircuitBreaker) after(gen int, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	if gen != b.gen {
+		// the call started before the last transition and says nothing
+		// about the current state
+		return
+	}
+
+	switch b.state {
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.setState(Open, now)
+			return
+		}
+		b.passed++
+		if b.passed >= b.trials {
+			b.setState(Closed, now)
+		}
+	case Closed:
+		b.record(now, failed)
+		successes, failures := b.counts(now)
+		total := successes + failures
+		if total >= b.minRequests && total > 0 && float64(failures)/float64(total) >= b.rate {
+			b.setState(Open, now)
+		}
+	}
+}
+
+// refresh moves from open to half-open once the timeout expired
+func (b *CircuitBreaker) refresh(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.openTimeout {
+		b.setState(HalfOpen, now)
+	}
+}
+
+func (b *CircuitBreaker) setState(to State, now time.Time) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.gen++
+	b.inFlight = 0
+	b.passed = 0
+	switch to {
+	case Open:
+		b.openedAt = now
+	case Closed:
+		b.buckets = nil
+	}
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
+
+func (b *CircuitBreaker) record(now time.Time, failed bool) {
+	size := b.window / bucketsPerWindow
+	if size <= 0 {
+		size = 1
+	}
+	start := now.Truncate(size)
+	if n := len(b.buckets); n == 0 || !b.buckets[n-1].start.Equal(start) {
+		b.buckets = append(b.buckets, bucket{start: start})
+	}
+	last := &b.buckets[len(b.buckets)-1]
+	if failed {
+		last.failures++
+	} else {
+		last.successes++
+	}
+}
+
+// counts drops expired buckets and sums the remaining ones
+func (b *CircuitBreaker) counts(now time.Time) (successes, failures int) {
+	cutoff := now.Add(-b.window)
+	i := 0
+	for i < len(b.buckets) && !b.buckets[i].start.After(cutoff) {
+		i++
+	}
+	b.buckets = b.buckets[i:]
+	for _, bk := range b.buckets {
+		successes += bk.successes
+		failures += bk.failures
+	}
+	return successes, failures
+}
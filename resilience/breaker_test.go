@@ -0,0 +1,149 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	var changes []string
+	b := NewCircuitBreaker(
+		WithClock(clock),
+		FailureRate(0.5, 4),
+		OpenTimeout(10*time.Second),
+		HalfOpenRequests(2),
+		OnStateChange(func(from, to State) {
+			changes = append(changes, from.String()+">"+to.String())
+		}),
+	)
+	fail := func() error { return errFlaky }
+	ok := func() error { return nil }
+
+	// 2 failures out of 4 calls reach the rate
+	for _, fn := range []func() error{ok, fail, ok} {
+		b.Execute(fn)
+	}
+	if b.State() != Closed {
+		t.Fatalf("opened after 3 calls")
+	}
+	b.Execute(fail)
+	if b.State() != Open {
+		t.Fatalf("state %v after 2 failures in 4 calls", b.State())
+	}
+	if err := b.Execute(ok); !errors.Is(err, ErrOpen) {
+		t.Errorf("open breaker returned %v", err)
+	}
+
+	clock.Advance(10 * time.Second)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after the open timeout", b.State())
+	}
+	// a failed trial opens again
+	b.Execute(fail)
+	if b.State() != Open {
+		t.Fatalf("state %v after a failed trial", b.State())
+	}
+
+	clock.Advance(10 * time.Second)
+	b.Execute(ok)
+	if b.State() != HalfOpen {
+		t.Fatalf("closed after 1 of 2 trials")
+	}
+	b.Execute(ok)
+	if b.State() != Closed {
+		t.Fatalf("state %v after 2 trials", b.State())
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("changes %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("changes %v, want %v", changes, want)
+			break
+		}
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	b := NewCircuitBreaker(WithClock(clock), FailureRate(0.5, 4), Window(time.Minute))
+	// old failures leave the window before they add up
+	for i := 0; i < 3; i++ {
+		b.Execute(func() error { return errFlaky })
+		clock.Advance(time.Minute)
+	}
+	b.Execute(func() error { return errFlaky })
+	if b.State() != Closed {
+		t.Errorf("opened on failures a minute apart")
+	}
+}
+
+func TestBreakerHalfOpenSlots(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	b := NewCircuitBreaker(WithClock(clock), FailureRate(1, 1), OpenTimeout(time.Second))
+	b.Execute(func() error { return errFlaky })
+	clock.Advance(time.Second)
+
+	// while the single trial runs, other calls are rejected
+	var inner error
+	err := b.Execute(func() error {
+		inner = b.Execute(func() error { return nil })
+		return nil
+	})
+	if err != nil || !errors.Is(inner, ErrOpen) {
+		t.Errorf("trial returned %v, concurrent call %v", err, inner)
+	}
+	if b.State() != Closed {
+		t.Errorf("state %v after the trial", b.State())
+	}
+}
+
+func TestBreakerIsFailure(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	notFound := errors.New("not found")
+	b := NewCircuitBreaker(WithClock(clock), FailureRate(0.5, 1), IsFailure(func(err error) bool {
+		return err != nil && !errors.Is(err, notFound)
+	}))
+	for i := 0; i < 10; i++ {
+		b.Execute(func() error { return notFound })
+	}
+	if b.State() != Closed {
+		t.Errorf("opened on errors that are not failures")
+	}
+}
+
+func TestBreakerPanickingTrial(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	b := NewCircuitBreaker(WithClock(clock), FailureRate(1, 1), OpenTimeout(time.Second))
+	b.Execute(func() error { return errFlaky })
+	clock.Advance(time.Second)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after the open timeout", b.State())
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic of the trial", r)
+			}
+		}()
+		b.Execute(func() error { panic("boom") })
+	}()
+	// the panic counts as a failed trial, and the next one gets its slot
+	if b.State() != Open {
+		t.Fatalf("state %v after a panicking trial", b.State())
+	}
+	clock.Advance(time.Second)
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("next trial: %v", err)
+	}
+	if b.State() != Closed {
+		t.Errorf("state %v after a good trial", b.State())
+	}
+}
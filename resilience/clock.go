@@ -0,0 +1,24 @@
+package resilience
+
+import "time"
+
+// Clock is the source of time of Retry and CircuitBreaker.
+// schedule.FakeClock satisfies it.
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the returned channel once d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+// RealClock uses the time package
+var RealClock Clock = realClock{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,140 @@
+// Package resilience helps calling dependencies that may fail: Retry repeats
+// an operation with a backoff, CircuitBreaker stops calling one that keeps
+// failing.
+//
+// Time comes from a Clock. With a schedule.FakeClock, waits last until the
+// fake clock is advanced past them, so tests run instantly.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the wait before the given attempt (starting at 1 for the
+// first retry). prev is the previous wait, zero on the first retry.
+type Backoff func(attempt int, prev time.Duration, rnd *rand.Rand) time.Duration
+
+// Constant always waits d
+func Constant(d time.Duration) Backoff {
+	return func(int, time.Duration, *rand.Rand) time.Duration {
+		return d
+	}
+}
+
+// Exponential waits base, base*factor, base*factor², ... up to max
+func Exponential(base, max time.Duration, factor float64) Backoff {
+	return func(attempt int, _ time.Duration, _ *rand.Rand) time.Duration {
+		d := float64(base) * math.Pow(factor, float64(attempt-1))
+		if max > 0 && d > float64(max) {
+			return max
+		}
+		return time.Duration(d)
+	}
+}
+
+// DecorrelatedJitter waits a random time between base and three times the
+// previous wait, capped at max. It spreads retries of many clients apart.
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	return func(_ int, prev time.Duration, rnd *rand.Rand) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		hi := 3 * prev
+		d := base + time.Duration(rnd.Int63n(int64(hi-base)+1))
+		if max > 0 && d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// Policy controls Retry
+type Policy struct {
+	Backoff     Backoff          // Constant(100ms) when nil
+	MaxAttempts int              // total calls, including the first; 0 means no limit
+	MaxElapsed  time.Duration    // give up instead of waiting past this; 0 means no limit
+	Retryable   func(error) bool // every error except Permanent ones when nil
+	OnRetry     func(attempt int, err error, wait time.Duration)
+	Clock       Clock      // RealClock when nil
+	Rand        *rand.Rand // used by jittered backoffs
+}
+
+// permanent marks an error that must not be retried
+type permanent struct {
+	err error
+}
+
+func (p permanent) Error() string { return p.err.Error() }
+func (p permanent) Unwrap() error { return p.err }
+
+// Permanent wraps err so that Retry returns it right away
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanent{err}
+}
+
+// IsPermanent tells if err was wrapped by Permanent
+func IsPermanent(err error) bool {
+	var p permanent
+	return errors.As(err, &p)
+}
+
+// ErrExhausted is matched by the error returned when Retry gives up
+var ErrExhausted = errors.New("retries exhausted")
+
+// Retry calls fn until it succeeds, returns a non retryable error, the policy
+// limits are reached or ctx is canceled
+func Retry(ctx context.Context, fn func(context.Context) error, p Policy) error {
+	if p.Backoff == nil {
+		p.Backoff = Constant(100 * time.Millisecond)
+	}
+	if p.Clock == nil {
+		p.Clock = RealClock
+	}
+	if p.Rand == nil {
+		p.Rand = rand.New(rand.NewSource(p.Clock.Now().UnixNano()))
+	}
+
+	start := p.Clock.Now()
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if IsPermanent(err) || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrExhausted, attempt, err)
+		}
+
+		wait = p.Backoff(attempt, wait, p.Rand)
+		if p.MaxElapsed > 0 && p.Clock.Now().Add(wait).Sub(start) > p.MaxElapsed {
+			return fmt.Errorf("%w after %s: %w", ErrExhausted, p.Clock.Now().Sub(start), err)
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		if cerr := sleep(ctx, p.Clock, wait); cerr != nil {
+			return fmt.Errorf("retry stopped: %w: %w", cerr, err)
+		}
+	}
+}
+
+// sleep waits d on the clock, unless ctx is done first
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,172 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"bitbucket.org/feliposz/go-by-example/schedule"
+)
+
+var start = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+var errFlaky = errors.New("flaky")
+
+// retry runs Retry in the background, advancing the fake clock each time it
+// waits, and returns the elapsed fake time of every call with its error
+func retry(t *testing.T, ctx context.Context, clock *schedule.FakeClock, fn func(context.Context) error, p Policy) ([]time.Duration, error) {
+	t.Helper()
+	p.Clock = clock
+	var calls []time.Duration
+	done := make(chan error, 1)
+	go func() {
+		done <- Retry(ctx, func(ctx context.Context) error {
+			calls = append(calls, clock.Now().Sub(start))
+			return fn(ctx)
+		}, p)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			return calls, err
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Retry did not return")
+		}
+		if clock.Pending() > 0 {
+			clock.Advance(time.Millisecond)
+		} else {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+}
+
+func failing(n int) func(context.Context) error {
+	return func(context.Context) error {
+		if n > 0 {
+			n--
+			return errFlaky
+		}
+		return nil
+	}
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	calls, err := retry(t, context.Background(), clock, failing(3), Policy{Backoff: Constant(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second}
+	if !equalDurations(calls, want) {
+		t.Errorf("calls at %v, want %v", calls, want)
+	}
+}
+
+func TestRetryExponential(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	var waits []time.Duration
+	_, err := retry(t, context.Background(), clock, failing(10), Policy{
+		Backoff:     Exponential(100*time.Millisecond, time.Second, 2),
+		MaxAttempts: 7,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			waits = append(waits, wait)
+		},
+	})
+	if !errors.Is(err, ErrExhausted) || !errors.Is(err, errFlaky) {
+		t.Errorf("got %v, want exhausted and flaky", err)
+	}
+	ms := time.Millisecond
+	want := []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms, time.Second, time.Second}
+	if !equalDurations(waits, want) {
+		t.Errorf("waits %v, want %v", waits, want)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	calls, err := retry(t, context.Background(), clock, failing(100), Policy{
+		Backoff:    Constant(time.Second),
+		MaxElapsed: 3500 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrExhausted) {
+		t.Errorf("got %v, want exhausted", err)
+	}
+	// a fifth call would start at 4s
+	if len(calls) != 4 {
+		t.Errorf("calls at %v, want 4 calls", calls)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	boom := errors.New("boom")
+	calls, err := retry(t, context.Background(), clock, func(context.Context) error {
+		return Permanent(boom)
+	}, Policy{})
+	if !errors.Is(err, boom) || !IsPermanent(err) || len(calls) != 1 {
+		t.Errorf("got %v after %d calls", err, len(calls))
+	}
+
+	calls, err = retry(t, context.Background(), clock, failing(5), Policy{
+		Retryable: func(err error) bool { return false },
+	})
+	if !errors.Is(err, errFlaky) || len(calls) != 1 {
+		t.Errorf("got %v after %d calls", err, len(calls))
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	clock := schedule.NewFakeClock(start)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Retry(ctx, failing(100), Policy{Clock: clock, Backoff: Constant(time.Hour)})
+	}()
+	// cancel while Retry waits for the clock, which never moves
+	for clock.Pending() == 0 {
+		time.Sleep(100 * time.Microsecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errFlaky) {
+			t.Errorf("got %v, want canceled and flaky", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Retry did not stop")
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	backoff := DecorrelatedJitter(100*time.Millisecond, 2*time.Second)
+	var prev time.Duration
+	for attempt := 1; attempt <= 50; attempt++ {
+		d := backoff(attempt, prev, rnd)
+		hi := 3 * prev
+		if hi < 300*time.Millisecond {
+			hi = 300 * time.Millisecond
+		}
+		if d < 100*time.Millisecond || d > hi || d > 2*time.Second {
+			t.Fatalf("attempt %d: %v after %v", attempt, d, prev)
+		}
+		prev = d
+	}
+}
@@ -0,0 +1,150 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by the scheduler
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call created by Clock.AfterFunc
+type Timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+// RealClock uses the time package
+var RealClock Clock = realClock{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// FakeClock only moves when told to. Pending functions run synchronously,
+// in time order, inside Advance and Jump, so a week of schedule can be
+// simulated in milliseconds.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	seq   int
+	f     func()
+}
+
+// NewFakeClock creates a clock stopped at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc registers f to run once the clock has advanced by d
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &fakeTimer{clock: c, when: c.now.Add(d), seq: c.seq, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// After returns a channel receiving the fake time once the clock has
+// advanced by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() {
+		ch <- c.Now()
+	})
+	return ch
+}
+
+// Pending returns the number of timers waiting for the clock to advance
+func (c *FakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Advance moves the clock forward by d, stopping at every pending timer on
+// the way so each one sees the exact time it was due. It may be called from
+// inside a timer function to simulate work that takes time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+	c.advanceTo(target, true)
+}
+
+// Jump moves the clock forward by d at once and only then runs the timers
+// that became due, as if the process had been suspended.
+func (c *FakeClock) Jump(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	target := c.now
+	c.mu.Unlock()
+	c.advanceTo(target, false)
+}
+
+func (c *FakeClock) advanceTo(target time.Time, step bool) {
+	for {
+		c.mu.Lock()
+		idx := -1
+		for i, t := range c.timers {
+			if t.when.After(target) {
+				continue
+			}
+			if idx < 0 || t.when.Before(c.timers[idx].when) ||
+				(t.when.Equal(c.timers[idx].when) && t.seq < c.timers[idx].seq) {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			if target.After(c.now) {
+				c.now = target
+			}
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[idx]
+		c.timers = append(c.timers[:idx], c.timers[idx+1:]...)
+		if step && t.when.After(c.now) {
+			c.now = t.when
+		}
+		c.mu.Unlock()
+		t.f()
+	}
+}
+
+// Stop cancels the timer, returning false if it already ran or was stopped
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
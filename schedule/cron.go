@@ -0,0 +1,238 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes activation times
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time if
+	// there is none
+	Next(t time.Time) time.Time
+}
+
+// field describes the valid values of one cron field
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = field{"second", 0, 59, nil}
+	minuteField = field{"minute", 0, 59, nil}
+	hourField   = field{"hour", 0, 23, nil}
+	domField    = field{"day of month", 1, 31, nil}
+	monthField  = field{"month", 1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as an alias for sunday
+	dowField = field{"day of week", 0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronSchedule keeps one bit per allowed value of each field
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// when one of the day fields is "*" both must match, otherwise either
+	domAny, dowAny bool
+
+	loc *time.Location
+}
+
+// Parse reads a cron expression with 5 fields (minute hour day-of-month month
+// day-of-week) or 6 fields (with a leading second). Each field accepts "*",
+// single values, ranges "a-b", steps "*/n" or "a-b/n" and comma separated
+// lists of those. Times are evaluated in the local time zone.
+func Parse(expr string) (Schedule, error) {
+	return ParseIn(expr, time.Local)
+}
+
+// ParseIn is like Parse but evaluates the expression in loc
+func ParseIn(expr string, loc *time.Location) (Schedule, error) {
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron: expected 5 or 6 fields, got %d in %q", len(fields), expr)
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	parsers := []struct {
+		bits *uint64
+		f    field
+	}{
+		{&s.second, secondField},
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	}
+	for i, p := range parsers {
+		if *p.bits, err = parseField(fields[i], p.f); err != nil {
+			return nil, err
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = isAny(fields[3])
+	s.dowAny = isAny(fields[5])
+	return s, nil
+}
+
+func isAny(f string) bool {
+	return f == "*" || f == "?"
+}
+
+// parseField converts a comma separated list into a bit set
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange handles "*", "a", "a-b" and an optional "/step" suffix
+func parseRange(expr string, f field) (uint64, error) {
+	rng, stepStr, hasStep := strings.Cut(expr, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepStr)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("cron: invalid step %q in %s field", stepStr, f.name)
+		}
+		step = n
+	}
+
+	var lo, hi int
+	var err error
+	switch {
+	case isAny(rng):
+		lo, hi = f.min, f.max
+	case strings.Contains(rng, "-"):
+		a, b, _ := strings.Cut(rng, "-")
+		if lo, err = parseValue(a, f); err != nil {
+			return 0, err
+		}
+		if hi, err = parseValue(b, f); err != nil {
+			return 0, err
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("cron: invalid range %q in %s field", rng, f.name)
+		}
+	default:
+		if lo, err = parseValue(rng, f); err != nil {
+			return 0, err
+		}
+		hi = lo
+		if hasStep {
+			// "a/n" means from a to the end in steps of n
+			hi = f.max
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("cron: invalid value %q in %s field (%d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next walks forward field by field, from the largest unit to the smallest
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := s.loc
+	if loc == nil {
+		loc = time.Local
+	}
+	orig := t.Location()
+	t = t.In(loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc).Add(time.Second)
+
+	// a matching date must exist within a few years (leap days at most)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if !next.After(t) {
+				// clocks were set back, skip the repeated hour
+				next = t.Truncate(time.Hour).Add(time.Hour)
+			}
+			t = next
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if !has(s.second, t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t.In(orig)
+	}
+	return time.Time{}
+}
+
+// every is a fixed interval schedule
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Every returns a schedule activating at a fixed interval. Like
+// time.NewTicker it panics if d is not positive.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("schedule: non-positive interval for Every")
+	}
+	return every(d)
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"1,,2 * * * *",
+	} {
+		if _, err := ParseIn(expr, time.UTC); err == nil {
+			t.Errorf("ParseIn(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Saturday
+	from := time.Date(2026, 1, 3, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 1, 3, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"*/20 30 10 * * *", time.Date(2026, 1, 3, 10, 30, 20, 0, time.UTC)},
+		{"0 0 1 feb *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// day of month and day of week both set: either matches
+		{"0 0 13 * fri", time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseIn(tt.expr, time.UTC)
+		if err != nil {
+			t.Fatalf("ParseIn(%q): %v", tt.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: Next(%v) = %v, want %v", tt.expr, from, got, tt.want)
+		}
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	s, err := ParseIn("0 9 * * *", ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// clocks move forward on 2026-03-08, so that day is 23 hours long
+	first := s.Next(time.Date(2026, 3, 7, 0, 0, 0, 0, ny))
+	second := s.Next(first)
+	if first.Hour() != 9 || second.In(ny).Hour() != 9 {
+		t.Fatalf("activations at %v and %v, want 9:00 local", first, second)
+	}
+	if d := second.Sub(first); d != 23*time.Hour {
+		t.Errorf("activations %v apart, want 23h", d)
+	}
+}
@@ -0,0 +1,402 @@
+// Package schedule runs jobs from cron expressions or at fixed intervals.
+//
+// All timing goes through a Clock. With a FakeClock jobs run synchronously
+// inside FakeClock.Advance, which makes schedules fully deterministic.
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MissedPolicy decides what to do when activations were missed, for example
+// because the process was suspended
+type MissedPolicy int
+
+const (
+	// RunOnce runs a single time no matter how many activations were missed
+	RunOnce MissedPolicy = iota
+	// RunAll runs once for every missed activation
+	RunAll
+	// Skip ignores late activations and waits for the next one
+	Skip
+)
+
+// maxCatchUp limits how many missed activations are counted after a long pause
+const maxCatchUp = 1000
+
+// Scheduler triggers jobs
+type Scheduler struct {
+	clock  Clock
+	loc    *time.Location
+	inline bool
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	jobs    map[*Job]struct{}
+	started bool
+	stopped bool // no run may start once set, guards wg.Add against Wait
+}
+
+// Option configures a scheduler
+type Option func(*Scheduler)
+
+// WithClock replaces the real clock, usually by a FakeClock in tests
+func WithClock(c Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// WithLocation sets the default time zone for cron expressions
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) {
+		s.loc = loc
+	}
+}
+
+// WithRand sets the random source used for jitter
+func WithRand(r *rand.Rand) Option {
+	return func(s *Scheduler) {
+		s.rand = r
+	}
+}
+
+// New creates a stopped scheduler
+func New(opts ...Option) *Scheduler {
+	s := &Scheduler{
+		clock: RealClock,
+		loc:   time.Local,
+		jobs:  make(map[*Job]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	_, s.inline = s.clock.(*FakeClock)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Job is a function registered in a scheduler
+type Job struct {
+	s        *Scheduler
+	name     string
+	schedule Schedule
+	delay    time.Duration
+	fn       func(context.Context)
+
+	jitter  time.Duration
+	missed  MissedPolicy
+	overlap bool
+	loc     *time.Location
+
+	mu      sync.Mutex
+	timer   Timer
+	due     time.Time
+	removed bool
+	running atomic.Bool
+
+	runs     atomic.Uint64
+	late     atomic.Uint64
+	overlaps atomic.Uint64
+}
+
+// JobOption configures a single job
+type JobOption func(*Job)
+
+// Name labels the job
+func Name(name string) JobOption {
+	return func(j *Job) {
+		j.name = name
+	}
+}
+
+// Jitter delays every activation by a random amount up to d
+func Jitter(d time.Duration) JobOption {
+	return func(j *Job) {
+		j.jitter = d
+	}
+}
+
+// Missed sets the missed activation policy (RunOnce by default)
+func Missed(p MissedPolicy) JobOption {
+	return func(j *Job) {
+		j.missed = p
+	}
+}
+
+// AllowOverlap lets a new run start while the previous one is still going.
+// By default such activations are skipped.
+func AllowOverlap() JobOption {
+	return func(j *Job) {
+		j.overlap = true
+	}
+}
+
+// In evaluates a cron expression in loc instead of the scheduler location
+func In(loc *time.Location) JobOption {
+	return func(j *Job) {
+		j.loc = loc
+	}
+}
+
+// JobStats holds the counters of a job
+type JobStats struct {
+	Runs     uint64 // times the function was called
+	Missed   uint64 // activations that were due while the clock was away
+	Overlaps uint64 // activations skipped because the job was still running
+}
+
+// Cron adds a job following a 5 or 6 field cron expression
+func (s *Scheduler) Cron(expr string, fn func(context.Context), opts ...JobOption) (*Job, error) {
+	j := s.newJob(fn, opts)
+	if j.loc == nil {
+		j.loc = s.loc
+	}
+	sched, err := ParseIn(expr, j.loc)
+	if err != nil {
+		return nil, err
+	}
+	j.schedule = sched
+	s.add(j)
+	return j, nil
+}
+
+// FixedRate adds a job that starts every d, regardless of how long it runs.
+// It panics if d is not positive.
+func (s *Scheduler) FixedRate(d time.Duration, fn func(context.Context), opts ...JobOption) *Job {
+	return s.Add(Every(d), fn, opts...)
+}
+
+// FixedDelay adds a job that starts d after the previous run finished. It
+// panics if d is not positive.
+func (s *Scheduler) FixedDelay(d time.Duration, fn func(context.Context), opts ...JobOption) *Job {
+	if d <= 0 {
+		panic("schedule: non-positive delay for FixedDelay")
+	}
+	j := s.newJob(fn, opts)
+	j.delay = d
+	s.add(j)
+	return j
+}
+
+// Add adds a job following any schedule
+func (s *Scheduler) Add(sched Schedule, fn func(context.Context), opts ...JobOption) *Job {
+	j := s.newJob(fn, opts)
+	j.schedule = sched
+	s.add(j)
+	return j
+}
+
+func (s *Scheduler) newJob(fn func(context.Context), opts []JobOption) *Job {
+	j := &Job{s: s, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+func (s *Scheduler) add(j *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[j] = struct{}{}
+	if s.started {
+		j.start()
+	}
+}
+
+// Start arms every job. Jobs added later start right away.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for j := range s.jobs {
+		j.start()
+	}
+}
+
+// Stop cancels the pending activations and waits for running jobs, whose
+// context is canceled
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.cancel()
+	for j := range s.jobs {
+		j.mu.Lock()
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+		j.mu.Unlock()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	s.randMu.Lock()
+	defer s.randMu.Unlock()
+	return time.Duration(s.rand.Int63n(int64(max) + 1))
+}
+
+// execute calls the job n times in a row, unless it would overlap
+func (s *Scheduler) execute(j *Job, n int, after func()) {
+	if n <= 0 {
+		return
+	}
+	// a timer may fire while Stop runs: register the run under s.mu so Stop
+	// either waits for it or it never starts
+	s.mu.Lock()
+	if s.stopped || s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return
+	}
+	if !j.overlap && !j.running.CompareAndSwap(false, true) {
+		s.mu.Unlock()
+		j.overlaps.Add(uint64(n))
+		return
+	}
+	if !s.inline {
+		s.wg.Add(1)
+	}
+	s.mu.Unlock()
+
+	run := func() {
+		if !j.overlap {
+			defer j.running.Store(false)
+		}
+		for i := 0; i < n && s.ctx.Err() == nil; i++ {
+			j.runs.Add(1)
+			j.fn(s.ctx)
+		}
+		if after != nil {
+			after()
+		}
+	}
+	if s.inline {
+		run()
+		return
+	}
+	go func() {
+		defer s.wg.Done()
+		run()
+	}()
+}
+
+// Name returns the job label
+func (j *Job) Name() string {
+	return j.name
+}
+
+// Next returns the time of the next activation, before jitter
+func (j *Job) Next() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.due
+}
+
+// Stats returns the job counters
+func (j *Job) Stats() JobStats {
+	return JobStats{
+		Runs:     j.runs.Load(),
+		Missed:   j.late.Load(),
+		Overlaps: j.overlaps.Load(),
+	}
+}
+
+// Remove unregisters the job. A run in progress is not interrupted.
+func (j *Job) Remove() {
+	j.mu.Lock()
+	j.removed = true
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	j.mu.Unlock()
+
+	j.s.mu.Lock()
+	delete(j.s.jobs, j)
+	j.s.mu.Unlock()
+}
+
+func (j *Job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.s.clock.Now()
+	if j.schedule == nil {
+		j.arm(now.Add(j.delay))
+	} else {
+		j.arm(j.schedule.Next(now))
+	}
+}
+
+// arm sets the timer for the activation at due. j.mu must be held.
+func (j *Job) arm(due time.Time) {
+	j.due = due
+	j.timer = nil
+	if due.IsZero() || j.removed || j.s.ctx.Err() != nil {
+		return
+	}
+	at := due.Add(j.s.randomJitter(j.jitter))
+	j.timer = j.s.clock.AfterFunc(at.Sub(j.s.clock.Now()), j.fire)
+}
+
+func (j *Job) fire() {
+	now := j.s.clock.Now()
+	j.mu.Lock()
+	if j.removed || j.s.ctx.Err() != nil {
+		j.mu.Unlock()
+		return
+	}
+
+	if j.schedule == nil {
+		j.mu.Unlock()
+		j.s.execute(j, 1, func() {
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			j.arm(j.s.clock.Now().Add(j.delay))
+		})
+		return
+	}
+
+	// count the activations that passed while we were away
+	missed := 0
+	next := j.schedule.Next(j.due)
+	for !next.IsZero() && !next.After(now) && missed < maxCatchUp {
+		missed++
+		next = j.schedule.Next(next)
+	}
+	if !next.IsZero() && !next.After(now) {
+		next = j.schedule.Next(now)
+	}
+	j.arm(next)
+	j.mu.Unlock()
+
+	runs := 1
+	if missed > 0 {
+		j.late.Add(uint64(missed))
+		switch j.missed {
+		case RunAll:
+			runs += missed
+		case Skip:
+			runs = 0
+		}
+	}
+	j.s.execute(j, runs, nil)
+}
@@ -0,0 +1,166 @@
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// monday is the start of the simulated weeks
+var monday = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+func TestWeekOfRuns(t *testing.T) {
+	clock := NewFakeClock(monday)
+	s := New(WithClock(clock), WithLocation(time.UTC))
+	var hourly, workdays int
+	if _, err := s.Cron("0 * * * *", func(context.Context) { hourly++ }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Cron("30 8 * * mon-fri", func(context.Context) { workdays++ }); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop()
+
+	start := time.Now()
+	clock.Advance(7 * 24 * time.Hour)
+	if hourly != 7*24 {
+		t.Errorf("hourly job ran %d times, want %d", hourly, 7*24)
+	}
+	if workdays != 5 {
+		t.Errorf("workday job ran %d times, want 5", workdays)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("simulating a week took %v", elapsed)
+	}
+}
+
+func TestMissedPolicies(t *testing.T) {
+	tests := []struct {
+		policy MissedPolicy
+		runs   uint64
+	}{
+		{RunOnce, 1},
+		{RunAll, 5},
+		{Skip, 0},
+	}
+	for _, tt := range tests {
+		clock := NewFakeClock(monday)
+		s := New(WithClock(clock))
+		j := s.Add(Every(time.Hour), func(context.Context) {}, Missed(tt.policy))
+		s.Start()
+
+		// suspended from 00:00 to 05:30: due at 01:00, missed 02:00 to 05:00
+		clock.Jump(5*time.Hour + 30*time.Minute)
+		st := j.Stats()
+		if st.Runs != tt.runs || st.Missed != 4 {
+			t.Errorf("policy %d: runs %d, missed %d, want %d and 4", tt.policy, st.Runs, st.Missed, tt.runs)
+		}
+		if next := j.Next(); !next.Equal(monday.Add(6 * time.Hour)) {
+			t.Errorf("policy %d: next activation %v, want 06:00", tt.policy, next)
+		}
+		s.Stop()
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		clock := NewFakeClock(monday)
+		s := New(WithClock(clock))
+		var calls, running, maxRunning int
+		var opts []JobOption
+		if allow {
+			opts = append(opts, AllowOverlap())
+		}
+		j := s.FixedRate(time.Minute, func(context.Context) {
+			calls++
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			if calls == 1 {
+				// the first run takes two and a half activations
+				clock.Advance(150 * time.Second)
+			}
+			running--
+		}, opts...)
+		s.Start()
+		clock.Advance(10 * time.Minute)
+		s.Stop()
+
+		st := j.Stats()
+		switch {
+		case !allow && (maxRunning != 1 || st.Overlaps != 2):
+			t.Errorf("overlap prevented: %d at once, %d overlaps, want 1 and 2", maxRunning, st.Overlaps)
+		case allow && maxRunning < 2:
+			t.Errorf("overlap allowed: never more than %d at once", maxRunning)
+		}
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	clock := NewFakeClock(monday)
+	s := New(WithClock(clock), WithLocation(time.UTC))
+	var at []time.Time
+	if _, err := s.Cron("0 9 * * *", func(context.Context) { at = append(at, clock.Now()) }, In(rome)); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop()
+	clock.Advance(24 * time.Hour)
+	// 9:00 in Rome is 8:00 UTC in winter
+	if len(at) != 1 || !at[0].Equal(monday.Add(8*time.Hour)) {
+		t.Errorf("ran at %v, want once at 08:00 UTC", at)
+	}
+}
+
+func TestNoRunAfterStop(t *testing.T) {
+	s := New()
+	var runs atomic.Int64
+	s.FixedRate(time.Millisecond, func(context.Context) { runs.Add(1) }, AllowOverlap())
+	s.Start()
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+	n := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if after := runs.Load(); after != n {
+		t.Errorf("%d runs started after Stop returned", after-n)
+	}
+
+	clock := NewFakeClock(monday)
+	fake := New(WithClock(clock))
+	j := fake.FixedRate(time.Minute, func(context.Context) {})
+	fake.Start()
+	fake.Stop()
+	clock.Advance(time.Hour)
+	if runs := j.Stats().Runs; runs != 0 {
+		t.Errorf("%d runs on a stopped scheduler", runs)
+	}
+}
+
+func TestNonPositiveInterval(t *testing.T) {
+	s := New(WithClock(NewFakeClock(monday)))
+	defer s.Stop()
+	calls := map[string]func(){
+		"Every(0)":       func() { Every(0) },
+		"Every(-1s)":     func() { Every(-time.Second) },
+		"FixedRate(0)":   func() { s.FixedRate(0, func(context.Context) {}) },
+		"FixedDelay(0)":  func() { s.FixedDelay(0, func(context.Context) {}) },
+		"FixedDelay(-1)": func() { s.FixedDelay(-1, func(context.Context) {}) },
+	}
+	for name, call := range calls {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			call()
+		}()
+	}
+}
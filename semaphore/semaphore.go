@@ -0,0 +1,146 @@
+// Package semaphore limits how much work runs at the same time.
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"bitbucket.org/feliposz/go-by-example/group"
+)
+
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// Weighted is a semaphore where each acquirer may take several units.
+// Waiters are served in arrival order, so a large request is not starved by
+// a stream of small ones.
+type Weighted struct {
+	size    int64
+	cur     int64
+	mu      sync.Mutex
+	waiters list.List
+}
+
+// NewWeighted creates a semaphore with n units
+func NewWeighted(n int64) *Weighted {
+	return &Weighted{size: n}
+}
+
+// Acquire takes n units, blocking until they are available or ctx is done
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	if n > s.size {
+		// can never succeed, wait for the context only
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	w := waiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-w.ready:
+			// granted just as ctx was canceled: give the units back
+			s.cur -= n
+			s.notify()
+		default:
+			front := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// leaving the head of the queue may unblock the ones behind
+			if front && s.size > s.cur {
+				s.notify()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes n units only if they are available right away
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release gives back n units
+func (s *Weighted) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notify()
+}
+
+// notify wakes waiters in order while they fit. s.mu must be held.
+func (s *Weighted) notify() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			// stop at the first one that doesn't fit to keep the order fair
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
+
+// ForEach calls fn for i from 0 to n-1 with at most limit calls running at
+// once, or all of them if limit is 0 or less. All errors are returned
+// joined; the first one cancels ctx for the remaining calls. If ctx ends
+// before every call started, its error is returned.
+func ForEach(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	sem := NewWeighted(int64(limit))
+	g, ctx := group.WithContext(ctx)
+	var stopped error
+	for i := 0; i < n; i++ {
+		// Acquire succeeds without looking at ctx when a unit is free
+		if err := ctx.Err(); err != nil {
+			stopped = err
+			break
+		}
+		if err := sem.Acquire(ctx, 1); err != nil {
+			stopped = err
+			break
+		}
+		i := i
+		g.Go(func(ctx context.Context) error {
+			defer sem.Release(1)
+			return fn(ctx, i)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return stopped
+}
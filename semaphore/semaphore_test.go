@@ -0,0 +1,115 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachLimit(t *testing.T) {
+	for _, limit := range []int{-1, 0, 1, 3, 100} {
+		var running, peak, calls atomic.Int64
+		err := ForEach(context.Background(), 20, limit, func(ctx context.Context, i int) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			calls.Add(1)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("limit %d: %v", limit, err)
+		}
+		if calls.Load() != 20 {
+			t.Errorf("limit %d: %d calls, want 20", limit, calls.Load())
+		}
+		if limit > 0 && peak.Load() > int64(limit) {
+			t.Errorf("limit %d: %d calls at once", limit, peak.Load())
+		}
+	}
+}
+
+func TestForEachError(t *testing.T) {
+	boom := errors.New("boom")
+	err := ForEach(context.Background(), 10, 1, func(ctx context.Context, i int) error {
+		if i == 2 {
+			return boom
+		}
+		return ctx.Err()
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("got %v, want %v", err, boom)
+	}
+}
+
+func TestForEachCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int64
+	err := ForEach(ctx, 10, 2, func(ctx context.Context, i int) error {
+		if calls.Add(1) == 2 {
+			cancel()
+		}
+		<-ctx.Done()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v after %d of 10 calls, want context.Canceled", err, calls.Load())
+	}
+	if calls.Load() == 10 {
+		t.Error("every call ran despite the cancellation")
+	}
+
+	// already canceled: nothing starts
+	calls.Store(0)
+	err = ForEach(ctx, 10, 0, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls.Load() != 0 {
+		t.Errorf("got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestWeightedOrder(t *testing.T) {
+	s := NewWeighted(3)
+	if !s.TryAcquire(2) {
+		t.Fatal("TryAcquire(2) failed on an empty semaphore")
+	}
+	big := make(chan struct{})
+	go func() {
+		s.Acquire(context.Background(), 3)
+		close(big)
+	}()
+	// wait for the big request to queue
+	for !func() bool { s.mu.Lock(); defer s.mu.Unlock(); return s.waiters.Len() == 1 }() {
+		time.Sleep(time.Millisecond)
+	}
+	// a unit is free, but the queued request comes first
+	if s.TryAcquire(1) {
+		t.Error("TryAcquire jumped the queue")
+	}
+	s.Release(2)
+	<-big
+	s.Release(3)
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	s := NewWeighted(1)
+	s.Acquire(context.Background(), 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+	s.Release(1)
+	if !s.TryAcquire(1) {
+		t.Error("unit lost by the canceled waiter")
+	}
+}
@@ -0,0 +1,163 @@
+// Package shardmap provides a concurrent map split into independently
+// locked shards, so goroutines touching different keys rarely contend.
+package shardmap
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+// ShardedMap is a map safe for concurrent use
+type ShardedMap[K comparable, V any] struct {
+	shards []shard[K, V]
+	hash   func(K) uint64
+}
+
+// Option configures a map
+type Option[K comparable] func(*options[K])
+
+type options[K comparable] struct {
+	shards int
+	hash   func(K) uint64
+}
+
+// WithShards sets the number of shards (32 by default)
+func WithShards[K comparable](n int) Option[K] {
+	return func(o *options[K]) {
+		o.shards = n
+	}
+}
+
+// WithHash replaces the hash function used to pick the shard of a key
+func WithHash[K comparable](hash func(K) uint64) Option[K] {
+	return func(o *options[K]) {
+		o.hash = hash
+	}
+}
+
+// New creates an empty map
+func New[K comparable, V any](opts ...Option[K]) *ShardedMap[K, V] {
+	o := options[K]{shards: 32}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.shards < 1 {
+		o.shards = 1
+	}
+	if o.hash == nil {
+		seed := maphash.MakeSeed()
+		o.hash = func(k K) uint64 {
+			return maphash.Comparable(seed, k)
+		}
+	}
+	m := &ShardedMap[K, V]{
+		shards: make([]shard[K, V], o.shards),
+		hash:   o.hash,
+	}
+	for i := range m.shards {
+		m.shards[i].m = make(map[K]V)
+	}
+	return m
+}
+
+func (m *ShardedMap[K, V]) shard(key K) *shard[K, V] {
+	return &m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// Get returns the value of key
+func (m *ShardedMap[K, V]) Get(key K) (V, bool) {
+	s := m.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// Set stores the value of key
+func (m *ShardedMap[K, V]) Set(key K, val V) {
+	s := m.shard(key)
+	s.mu.Lock()
+	s.m[key] = val
+	s.mu.Unlock()
+}
+
+// Delete removes key
+func (m *ShardedMap[K, V]) Delete(key K) {
+	s := m.shard(key)
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+}
+
+// Len returns the number of keys. Shards are counted one at a time, so the
+// result may be off while other goroutines are writing.
+func (m *ShardedMap[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		n += len(s.m)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// GetOrSet returns the existing value of key, or stores and returns val.
+// loaded tells which one happened.
+func (m *ShardedMap[K, V]) GetOrSet(key K, val V) (actual V, loaded bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.m[key]; ok {
+		return v, true
+	}
+	s.m[key] = val
+	return val, false
+}
+
+// Compute replaces the value of key with the result of fn, atomically.
+// fn receives the current value and whether it exists; returning keep false
+// deletes the key. fn runs with the shard locked and must not use the map.
+func (m *ShardedMap[K, V]) Compute(key K, fn func(old V, exists bool) (val V, keep bool)) (V, bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.m[key]
+	val, keep := fn(old, ok)
+	if !keep {
+		delete(s.m, key)
+		return val, false
+	}
+	s.m[key] = val
+	return val, true
+}
+
+// Range calls fn for every key until it returns false. Each shard is copied
+// under its lock and fn runs on that snapshot, so it sees a consistent view
+// of each shard (not of the whole map) and may safely use the map.
+func (m *ShardedMap[K, V]) Range(fn func(key K, val V) bool) {
+	type entry struct {
+		k K
+		v V
+	}
+	var snapshot []entry
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		snapshot = snapshot[:0]
+		for k, v := range s.m {
+			snapshot = append(snapshot, entry{k, v})
+		}
+		s.mu.RUnlock()
+		for _, e := range snapshot {
+			if !fn(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package shardmap
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestBasics(t *testing.T) {
+	m := New[string, int]()
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("empty map has a")
+	}
+	m.Set("a", 1)
+	m.Set("b", 2)
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	if m.Len() != 2 {
+		t.Errorf("Len = %d, want 2", m.Len())
+	}
+	m.Delete("a")
+	if _, ok := m.Get("a"); ok || m.Len() != 1 {
+		t.Errorf("a not deleted, Len = %d", m.Len())
+	}
+
+	if v, loaded := m.GetOrSet("b", 5); !loaded || v != 2 {
+		t.Errorf("GetOrSet(b) = %d, %v, want 2, true", v, loaded)
+	}
+	if v, loaded := m.GetOrSet("c", 3); loaded || v != 3 {
+		t.Errorf("GetOrSet(c) = %d, %v, want 3, false", v, loaded)
+	}
+
+	inc := func(old int, exists bool) (int, bool) { return old + 1, true }
+	if v, ok := m.Compute("c", inc); !ok || v != 4 {
+		t.Errorf("Compute(c) = %d, %v, want 4, true", v, ok)
+	}
+	if v, ok := m.Compute("d", inc); !ok || v != 1 {
+		t.Errorf("Compute(d) = %d, %v, want 1, true", v, ok)
+	}
+	drop := func(old int, exists bool) (int, bool) { return 0, false }
+	if _, ok := m.Compute("c", drop); ok {
+		t.Error("Compute kept c")
+	}
+	if _, ok := m.Get("c"); ok {
+		t.Error("c not deleted by Compute")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	// a constant hash puts everything in one shard
+	m := New[int, int](WithShards[int](0), WithHash(func(int) uint64 { return 7 }))
+	if len(m.shards) != 1 {
+		t.Errorf("%d shards, want at least 1", len(m.shards))
+	}
+	m = New[int, int](WithShards[int](4), WithHash(func(int) uint64 { return 7 }))
+	for i := 0; i < 10; i++ {
+		m.Set(i, i)
+	}
+	if len(m.shards[3].m) != 10 {
+		t.Errorf("shard 3 holds %d keys, want 10", len(m.shards[3].m))
+	}
+}
+
+func TestRange(t *testing.T) {
+	m := New[int, int](WithShards[int](4))
+	for i := 0; i < 100; i++ {
+		m.Set(i, i*i)
+	}
+	seen := make(map[int]bool)
+	m.Range(func(k, v int) bool {
+		if k >= 1000 {
+			// added below, in a shard not visited yet
+			return true
+		}
+		if v != k*k {
+			t.Errorf("%d: %d", k, v)
+		}
+		seen[k] = true
+		// fn may use the map
+		m.Set(k+1000, 0)
+		return true
+	})
+	if len(seen) != 100 {
+		t.Errorf("Range saw %d keys, want 100", len(seen))
+	}
+
+	n := 0
+	m.Range(func(k, v int) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Errorf("Range went on after false: %d calls", n)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	const goroutines, perGoroutine = 8, 1000
+	m := New[int, int]()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				key := g*perGoroutine + i
+				m.Set(key, i)
+				m.Get(key - 1)
+				m.Compute(-1, func(old int, _ bool) (int, bool) { return old + 1, true })
+				if i%10 == 0 {
+					m.Delete(key)
+				}
+				if i%100 == 0 {
+					m.Len()
+					m.Range(func(int, int) bool { return true })
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if v, _ := m.Get(-1); v != goroutines*perGoroutine {
+		t.Errorf("counter %d, want %d", v, goroutines*perGoroutine)
+	}
+	// every tenth key was deleted, plus the counter
+	if want := goroutines*perGoroutine*9/10 + 1; m.Len() != want {
+		t.Errorf("Len = %d, want %d", m.Len(), want)
+	}
+}
+
+// mutexMap is the baseline: one lock for the whole map
+type mutexMap struct {
+	mu sync.RWMutex
+	m  map[int]int
+}
+
+func (m *mutexMap) Get(key int) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.m[key]
+	return v, ok
+}
+
+func (m *mutexMap) Set(key, val int) {
+	m.mu.Lock()
+	m.m[key] = val
+	m.mu.Unlock()
+}
+
+type benchMap interface {
+	Get(key int) (int, bool)
+	Set(key, val int)
+}
+
+// benchReadPercents are the read/write ratios of the benchmarks
+var benchReadPercents = []int{50, 90, 99}
+
+func benchmarkMap(b *testing.B, newMap func() benchMap) {
+	const keys = 1024
+	for _, reads := range benchReadPercents {
+		b.Run(fmt.Sprintf("reads=%d%%", reads), func(b *testing.B) {
+			m := newMap()
+			for k := 0; k < keys; k++ {
+				m.Set(k, k)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(rand.Int63()))
+				for i := 0; pb.Next(); i++ {
+					key := rng.Intn(keys)
+					if rng.Intn(100) < reads {
+						m.Get(key)
+					} else {
+						m.Set(key, i)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkShardedMap(b *testing.B) {
+	benchmarkMap(b, func() benchMap { return New[int, int]() })
+}
+
+func BenchmarkMutexMap(b *testing.B) {
+	benchmarkMap(b, func() benchMap { return &mutexMap{m: make(map[int]int)} })
+}
@@ -0,0 +1,182 @@
+package stores
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Scenario describes a workload
+type Scenario struct {
+	ReadPercent int // share of operations that are reads, 0 to 100
+	Keys        int // number of distinct keys
+	Goroutines  int // goroutines sharing the store
+}
+
+func (s Scenario) String() string {
+	return fmt.Sprintf("reads=%d%% keys=%d goroutines=%d", s.ReadPercent, s.Keys, s.Goroutines)
+}
+
+// Scenarios returns every combination of the given parameters
+func Scenarios(readPercents, keys, goroutines []int) []Scenario {
+	var list []Scenario
+	for _, r := range readPercents {
+		for _, k := range keys {
+			for _, g := range goroutines {
+				list = append(list, Scenario{ReadPercent: r, Keys: k, Goroutines: g})
+			}
+		}
+	}
+	return list
+}
+
+// DefaultScenarios covers write heavy to read mostly loads. The 100 readers
+// and 10 writers of mutexExample are close to reads=90% keys=8 goroutines=64.
+func DefaultScenarios() []Scenario {
+	return Scenarios([]int{50, 90, 99}, []int{8, 1024}, []int{1, 8, 64})
+}
+
+// Validate reports a scenario that can't run, such as one without keys
+func (s Scenario) Validate() error {
+	switch {
+	case s.ReadPercent < 0 || s.ReadPercent > 100:
+		return fmt.Errorf("stores: %v: read percent must be between 0 and 100", s)
+	case s.Keys < 1:
+		return fmt.Errorf("stores: %v: at least one key is needed", s)
+	case s.Goroutines < 0:
+		return fmt.Errorf("stores: %v: negative goroutines", s)
+	}
+	return nil
+}
+
+// Fill sets every key of the scenario, so reads find a value
+func Fill(s Store, sc Scenario) {
+	for k := 0; k < sc.Keys; k++ {
+		s.Set(k, k)
+	}
+}
+
+// Work runs ops operations of the scenario on s, split among its goroutines.
+// The scenario must be valid.
+func Work(s Store, sc Scenario, ops int) {
+	goroutines := sc.Goroutines
+	if goroutines < 1 {
+		goroutines = 1
+	}
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		n := ops / goroutines
+		if g < ops%goroutines {
+			n++
+		}
+		go func(seed int64, n int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < n; i++ {
+				key := rng.Intn(sc.Keys)
+				if rng.Intn(100) < sc.ReadPercent {
+					s.Get(key)
+				} else {
+					s.Set(key, i)
+				}
+			}
+		}(int64(g), n)
+	}
+	wg.Wait()
+}
+
+// Measure returns the average time of one operation of the scenario. Like a
+// benchmark it grows the number of operations until a run lasts benchtime.
+func Measure(newStore func() Store, sc Scenario, benchtime time.Duration) (time.Duration, error) {
+	if err := sc.Validate(); err != nil {
+		return 0, err
+	}
+	s := newStore()
+	if closer, ok := s.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	Fill(s, sc)
+	for ops := 100; ; ops *= 2 {
+		start := time.Now()
+		Work(s, sc, ops)
+		elapsed := time.Since(start)
+		if elapsed >= benchtime || ops >= 1<<30 {
+			return elapsed / time.Duration(ops), nil
+		}
+	}
+}
+
+// Result holds the measures of every store for one scenario
+type Result struct {
+	Scenario Scenario
+	Stores   []string
+	NsPerOp  []int64
+}
+
+// Run measures every store on every scenario, each for about benchtime
+func Run(stores []func() Store, scenarios []Scenario, benchtime time.Duration) ([]Result, error) {
+	for _, sc := range scenarios {
+		if err := sc.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	var results []Result
+	for _, sc := range scenarios {
+		r := Result{Scenario: sc}
+		for _, newStore := range stores {
+			perOp, err := Measure(newStore, sc, benchtime)
+			if err != nil {
+				return nil, err
+			}
+			r.Stores = append(r.Stores, name(newStore))
+			r.NsPerOp = append(r.NsPerOp, perOp.Nanoseconds())
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func name(newStore func() Store) string {
+	s := newStore()
+	if closer, ok := s.(interface{ Close() }); ok {
+		closer.Close()
+	}
+	return s.Name()
+}
+
+// PrintReport writes a table with the ns/op of every store, marking the
+// fastest of each scenario with a star
+func PrintReport(w io.Writer, results []Result) {
+	if len(results) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "reads\tkeys\tgoroutines\t")
+	for _, name := range results[0].Stores {
+		fmt.Fprintf(tw, "%s\t", name)
+	}
+	fmt.Fprintln(tw)
+
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d%%\t%d\t%d\t", r.Scenario.ReadPercent, r.Scenario.Keys, r.Scenario.Goroutines)
+		best := 0
+		for i, ns := range r.NsPerOp {
+			if ns < r.NsPerOp[best] {
+				best = i
+			}
+		}
+		for i, ns := range r.NsPerOp {
+			mark := " "
+			if i == best {
+				mark = "*"
+			}
+			fmt.Fprintf(tw, "%d ns/op%s\t", ns, mark)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,77 @@
+package stores
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchmarkStore runs every default scenario on the stores built by newStore
+func benchmarkStore(b *testing.B, newStore func() Store) {
+	for _, sc := range DefaultScenarios() {
+		b.Run(fmt.Sprintf("reads=%d/keys=%d/goroutines=%d", sc.ReadPercent, sc.Keys, sc.Goroutines), func(b *testing.B) {
+			s := newStore()
+			if closer, ok := s.(interface{ Close() }); ok {
+				defer closer.Close()
+			}
+			Fill(s, sc)
+			b.ResetTimer()
+			Work(s, sc, b.N)
+		})
+	}
+}
+
+func BenchmarkMutex(b *testing.B)   { benchmarkStore(b, NewMutex) }
+func BenchmarkRWMutex(b *testing.B) { benchmarkStore(b, NewRWMutex) }
+func BenchmarkSyncMap(b *testing.B) { benchmarkStore(b, NewSyncMap) }
+func BenchmarkSharded(b *testing.B) { benchmarkStore(b, NewSharded) }
+func BenchmarkActor(b *testing.B)   { benchmarkStore(b, NewActor) }
+
+func TestStores(t *testing.T) {
+	for _, newStore := range All {
+		s := newStore()
+		if _, ok := s.Get(1); ok {
+			t.Errorf("%s: empty store has key 1", s.Name())
+		}
+		Fill(s, Scenario{Keys: 10})
+		Work(s, Scenario{ReadPercent: 50, Keys: 10, Goroutines: 4}, 1000)
+		s.Set(3, 42)
+		if v, ok := s.Get(3); !ok || v != 42 {
+			t.Errorf("%s: Get(3) = %d, %v, want 42, true", s.Name(), v, ok)
+		}
+		if closer, ok := s.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []Scenario{
+		{ReadPercent: 90, Keys: 0, Goroutines: 1},
+		{ReadPercent: 101, Keys: 8, Goroutines: 1},
+		{ReadPercent: -1, Keys: 8, Goroutines: 1},
+		{ReadPercent: 90, Keys: 8, Goroutines: -1},
+	}
+	for _, sc := range bad {
+		if err := sc.Validate(); err == nil {
+			t.Errorf("%v: no error", sc)
+		}
+	}
+	for _, sc := range DefaultScenarios() {
+		if err := sc.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := Run(All, []Scenario{{ReadPercent: 90}}, 0); err == nil {
+		t.Error("Run accepted a scenario without keys")
+	}
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(All, []Scenario{{ReadPercent: 90, Keys: 8, Goroutines: 2}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].NsPerOp) != len(All) {
+		t.Fatalf("got %+v", results)
+	}
+}
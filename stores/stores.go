@@ -0,0 +1,199 @@
+// Package stores holds the shared map of mutexExample and statefulExample
+// behind different synchronization strategies so they can be benchmarked.
+package stores
+
+import (
+	"sync"
+
+	"bitbucket.org/feliposz/go-by-example/shardmap"
+)
+
+// Store is a map of int keys to int values safe for concurrent use
+type Store interface {
+	Name() string
+	Get(key int) (int, bool)
+	Set(key, val int)
+}
+
+// Mutex guards a map with sync.Mutex, like mutexExample
+type Mutex struct {
+	mu sync.Mutex
+	m  map[int]int
+}
+
+// NewMutex creates an empty store
+func NewMutex() Store {
+	return &Mutex{m: make(map[int]int)}
+}
+
+// Name of the store
+func (s *Mutex) Name() string { return "Mutex" }
+
+// Get reads a key
+func (s *Mutex) Get(key int) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// Set writes a key
+func (s *Mutex) Set(key, val int) {
+	s.mu.Lock()
+	s.m[key] = val
+	s.mu.Unlock()
+}
+
+// RWMutex lets readers proceed in parallel
+type RWMutex struct {
+	mu sync.RWMutex
+	m  map[int]int
+}
+
+// NewRWMutex creates an empty store
+func NewRWMutex() Store {
+	return &RWMutex{m: make(map[int]int)}
+}
+
+// Name of the store
+func (s *RWMutex) Name() string { return "RWMutex" }
+
+// Get reads a key
+func (s *RWMutex) Get(key int) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// Set writes a key
+func (s *RWMutex) Set(key, val int) {
+	s.mu.Lock()
+	s.m[key] = val
+	s.mu.Unlock()
+}
+
+// SyncMap uses sync.Map
+type SyncMap struct {
+	m sync.Map
+}
+
+// NewSyncMap creates an empty store
+func NewSyncMap() Store {
+	return &SyncMap{}
+}
+
+// Name of the store
+func (s *SyncMap) Name() string { return "sync.Map" }
+
+// Get reads a key
+func (s *SyncMap) Get(key int) (int, bool) {
+	v, ok := s.m.Load(key)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+// Set writes a key
+func (s *SyncMap) Set(key, val int) {
+	s.m.Store(key, val)
+}
+
+// Sharded uses shardmap.ShardedMap, which hashes each key to one of several
+// shards with their own lock
+type Sharded struct {
+	m *shardmap.ShardedMap[int, int]
+}
+
+// NewSharded creates an empty store with 32 shards
+func NewSharded() Store {
+	return &Sharded{m: shardmap.New[int, int](shardmap.WithShards[int](32))}
+}
+
+// Name of the store
+func (s *Sharded) Name() string { return "ShardedMap" }
+
+// Get reads a key
+func (s *Sharded) Get(key int) (int, bool) {
+	return s.m.Get(key)
+}
+
+// Set writes a key
+func (s *Sharded) Set(key, val int) {
+	s.m.Set(key, val)
+}
+
+// Actor keeps the map private to one goroutine, like statefulExample
+type Actor struct {
+	reads  chan readOp
+	writes chan writeOp
+	quit   chan struct{}
+}
+
+type readOp struct {
+	key  int
+	resp chan readResult
+}
+
+type readResult struct {
+	val int
+	ok  bool
+}
+
+type writeOp struct {
+	key  int
+	val  int
+	resp chan bool
+}
+
+// NewActor starts the goroutine owning the map. Close stops it.
+func NewActor() Store {
+	s := &Actor{
+		reads:  make(chan readOp),
+		writes: make(chan writeOp),
+		quit:   make(chan struct{}),
+	}
+	go func() {
+		state := make(map[int]int)
+		for {
+			select {
+			case read := <-s.reads:
+				v, ok := state[read.key]
+				read.resp <- readResult{v, ok}
+			case write := <-s.writes:
+				state[write.key] = write.val
+				write.resp <- true
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Name of the store
+func (s *Actor) Name() string { return "goroutine owned" }
+
+// Get asks the owner for a key
+func (s *Actor) Get(key int) (int, bool) {
+	read := readOp{key: key, resp: make(chan readResult, 1)}
+	s.reads <- read
+	r := <-read.resp
+	return r.val, r.ok
+}
+
+// Set asks the owner to write a key
+func (s *Actor) Set(key, val int) {
+	write := writeOp{key: key, val: val, resp: make(chan bool, 1)}
+	s.writes <- write
+	<-write.resp
+}
+
+// Close stops the owner goroutine
+func (s *Actor) Close() {
+	close(s.quit)
+}
+
+// All lists the constructors of every store
+var All = []func() Store{NewMutex, NewRWMutex, NewSyncMap, NewSharded, NewActor}